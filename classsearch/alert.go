@@ -0,0 +1,407 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lyokum/update"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SnapshotFilename = "watch_snapshot.json"
+
+	PrintNotifier  = "print"
+	UpdateNotifier = "update"
+	TextNotifier   = "text"
+)
+
+var (
+	ErrUnknownCondition = errors.New("Unknown alert condition (use open, opened, capacity, instructor, meeting or cancelled)")
+	ErrUnknownNotifier  = errors.New("Unknown notifier (use print, update or text)")
+	ErrNotifierOff      = errors.New("Alert rule uses a notifier that is not set up (see --update and --text)")
+	ErrBadThreshold     = errors.New("Only the open condition takes a threshold")
+
+	// e.g. "open>=5:text,print"
+	rulePattern = regexp.MustCompile(`^(\w+)\s*(?:>=\s*(\d+))?\s*(?::\s*([\w,\s]+))?$`)
+
+	// alert when a section has any open seat, like check always has
+	DefaultRules = []AlertRule{{Condition: OpenSeats, Threshold: 1}}
+
+	Notifiers = []string{PrintNotifier, UpdateNotifier, TextNotifier}
+)
+
+// Condition is a change in a watched section that can raise an alert.
+type Condition int
+
+const (
+	OpenSeats         Condition = iota // Open at least Threshold
+	SeatsOpened                        // Open increased since last check
+	CapacityChanged                    // Max changed
+	InstructorChanged                  // Instructor changed
+	MeetingChanged                     // Time or Location changed
+	SectionCancelled                   // section no longer offered
+)
+
+var conditionNames = []string{"open", "opened", "capacity", "instructor", "meeting", "cancelled"}
+var conditionSubjects = []string{"<CLASS OPENING>", "<CLASS OPENING>", "<CLASS CAPACITY>", "<CLASS INSTRUCTOR>", "<CLASS MOVED>", "<CLASS CANCELLED>"}
+
+// AlertRule is a condition and the notifiers its alerts are sent through.
+type AlertRule struct {
+	Condition Condition
+	Threshold int      // minimum open seats for OpenSeats
+	Notifiers []string // every notifier set up if empty
+}
+
+type Alert struct {
+	Rule    AlertRule
+	Class   Class  // last seen if cancelled
+	Subject string // defaults to one for the condition
+	Message string
+}
+
+// Snapshot is the sections of each watch target at the last check, used to
+// tell what changed since.
+type Snapshot struct {
+	Timestamp time.Time
+	Sections  map[string][]Class // keyed by target name
+}
+
+/* Condition Functions */
+func ParseCondition(name string) (condition Condition, err error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, conditionName := range conditionNames {
+		if name == conditionName {
+			return Condition(i), nil
+		}
+	}
+
+	return condition, ErrUnknownCondition
+}
+
+/* Condition Receivers */
+func (condition Condition) String() string {
+	if condition < 0 || int(condition) >= len(conditionNames) {
+		return "unknown"
+	}
+	return conditionNames[condition]
+}
+
+/* AlertRule Functions */
+func ParseAlertRule(text string) (rule AlertRule, err error) {
+	match := rulePattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(text)))
+	if match == nil {
+		return rule, ErrUnknownCondition
+	}
+
+	rule.Condition, err = ParseCondition(match[1])
+	if err != nil {
+		return
+	}
+
+	// threshold only applies to open seats
+	if match[2] != "" {
+		if rule.Condition != OpenSeats {
+			return rule, ErrBadThreshold
+		}
+		rule.Threshold, _ = strconv.Atoi(match[2])
+	}
+
+	if match[3] != "" {
+		for _, notifier := range strings.Split(match[3], ",") {
+			notifier = strings.TrimSpace(notifier)
+			if !containsString(Notifiers, notifier) {
+				return rule, ErrUnknownNotifier
+			}
+			rule.Notifiers = append(rule.Notifiers, notifier)
+		}
+	}
+
+	return rule, nil
+}
+
+func ParseAlertRules(texts []string) (rules []AlertRule, err error) {
+	for _, text := range texts {
+		rule, err := ParseAlertRule(text)
+		if err != nil {
+			return rules, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+/* AlertRule Receivers */
+func (rule AlertRule) String() (text string) {
+	text = rule.Condition.String()
+	if rule.Condition == OpenSeats && rule.Threshold > 1 {
+		text += ">=" + strconv.Itoa(rule.Threshold)
+	}
+
+	if len(rule.Notifiers) > 0 {
+		text += ":" + strings.Join(rule.Notifiers, ",")
+	}
+	return text
+}
+
+func (rule AlertRule) Uses(notifier string) bool {
+	return len(rule.Notifiers) == 0 || containsString(rule.Notifiers, notifier)
+}
+
+// CheckNotifiers makes sure notifiers picked by rule are set up in info.
+func (rule AlertRule) CheckNotifiers(info NotifInfo) (err error) {
+	for _, notifier := range rule.Notifiers {
+		if (notifier == UpdateNotifier && !info.SendUpdate) || (notifier == TextNotifier && !info.SendText) {
+			return ErrNotifierOff
+		}
+	}
+	return nil
+}
+
+// Evaluate checks rule against a section now and at the last check, either
+// of which is nil if the section wasn't there.
+func (rule AlertRule) Evaluate(previous, current *Class) (alert Alert, fired bool) {
+	alert.Rule = rule
+
+	switch {
+	case current == nil && previous == nil:
+		return alert, false
+	case current == nil:
+		// cancelled is the only rule for missing sections
+		alert.Class = *previous
+		if rule.Condition == SectionCancelled {
+			alert.Message = fmt.Sprintf("%s was cancelled", sectionLabel(*previous))
+			return alert, true
+		}
+		return alert, false
+	}
+
+	alert.Class = *current
+	label := sectionLabel(*current)
+
+	// sections added since last check start with no seats
+	if previous == nil && rule.Condition == SeatsOpened {
+		previous = &Class{}
+	}
+
+	switch rule.Condition {
+	case OpenSeats:
+		threshold := rule.Threshold
+		if threshold < 1 {
+			threshold = 1
+		}
+		alert.Message = fmt.Sprintf("%s has %d open slot(s)!", label, current.Open)
+		return alert, current.Open >= threshold
+	}
+
+	// everything else compares with the last check
+	if previous == nil {
+		return alert, false
+	}
+
+	switch rule.Condition {
+	case SeatsOpened:
+		alert.Message = fmt.Sprintf("%s open slots went from %d to %d", label, previous.Open, current.Open)
+		return alert, current.Open > previous.Open
+	case CapacityChanged:
+		alert.Message = fmt.Sprintf("%s capacity changed from %d to %d", label, previous.Max, current.Max)
+		return alert, current.Max != previous.Max
+	case InstructorChanged:
+		alert.Message = fmt.Sprintf("%s instructor changed from %s to %s", label, previous.Instructor, current.Instructor)
+		return alert, current.Instructor != previous.Instructor
+	case MeetingChanged:
+		alert.Message = fmt.Sprintf("%s moved from %s in %s to %s in %s", label, previous.Time, previous.Location, current.Time, current.Location)
+		return alert, current.Time != previous.Time || current.Location != previous.Location
+	}
+
+	return alert, false
+}
+
+/* Alert Functions */
+// SendAlerts sends each of alerts through the notifiers of its rule and
+// waits for them to go out.
+func SendAlerts(info NotifInfo, alerts []Alert) {
+	wg := &sync.WaitGroup{}
+	for _, alert := range alerts {
+		alert.Notify(info, wg)
+	}
+	wg.Wait()
+}
+
+/* Alert Receivers */
+// Notify sends alert through the update and text notifiers of its rule.
+// Printing is left to the caller, see Printed.
+func (alert Alert) Notify(info NotifInfo, wg *sync.WaitGroup) {
+	// create update
+	var notif update.Update
+	notif.Subject = alert.Subject
+	if notif.Subject == "" {
+		notif.Subject = conditionSubjects[alert.Rule.Condition]
+	}
+	notif.Body = alert.Message
+
+	// send update
+	if info.SendUpdate && alert.Rule.Uses(UpdateNotifier) {
+		wg.Add(1)
+		go func(notif update.Update, info NotifInfo) {
+			notif.SendRequest(info.Server)
+			wg.Done()
+		}(notif, info)
+	}
+
+	// send text
+	if info.SendText && alert.Rule.Uses(TextNotifier) {
+		wg.Add(1)
+		go func(class Class, notif update.Update, info NotifInfo) {
+			class.sendText(notif, info)
+			wg.Done()
+		}(alert.Class, notif, info)
+	}
+}
+
+// Printed tells whether alert goes to the print notifier. Rules without
+// notifiers print alongside updates, as check always has.
+func (alert Alert) Printed(info NotifInfo) bool {
+	return containsString(alert.Rule.Notifiers, PrintNotifier) || (len(alert.Rule.Notifiers) == 0 && info.SendUpdate)
+}
+
+/* Snapshot Functions */
+func NewSnapshot() (snapshot Snapshot) {
+	return Snapshot{Sections: make(map[string][]Class)}
+}
+
+/* Snapshot Receivers */
+// Evaluate checks the rules of target against its sections now, where polled
+// is everything fetched with them. Only sections missing from polled while
+// their subject was fetched count as cancelled.
+func (snapshot Snapshot) Evaluate(target WatchTarget, sections ClassList, polled ClassList) (alerts []Alert) {
+	previous, seen := snapshot.Sections[target.Name()]
+
+	last := make(map[int]*Class)
+	for i := range previous {
+		last[previous[i].CRN] = &previous[i]
+	}
+
+	subjects := make(map[string]bool)
+	for _, class := range polled.List {
+		subjects[class.GetSubject()] = true
+	}
+
+	for _, rule := range target.AlertRules() {
+		for _, class := range sections.List {
+			// changes can't be told on the first check
+			if !seen && rule.Condition != OpenSeats {
+				continue
+			}
+
+			current := class
+			if alert, fired := rule.Evaluate(last[class.CRN], &current); fired {
+				alerts = append(alerts, alert)
+			}
+		}
+
+		// sections gone since last check
+		for i := range previous {
+			class := previous[i]
+			if _, ok := sections.Map[class.CRN]; ok {
+				continue
+			} else if _, ok := polled.Map[class.CRN]; ok || !subjects[class.GetSubject()] {
+				// still offered but filtered out, or subject not fetched
+				continue
+			}
+
+			if alert, fired := rule.Evaluate(&class, nil); fired {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+
+	return alerts
+}
+
+// Update replaces the sections of target with the current ones.
+func (snapshot *Snapshot) Update(target WatchTarget, sections ClassList) {
+	if snapshot.Sections == nil {
+		snapshot.Sections = make(map[string][]Class)
+	}
+
+	classes := make([]Class, 0, len(sections.List))
+	for _, class := range sections.List {
+		// details aren't compared, keep snapshot small
+		class.Detail = nil
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i].CRN < classes[j].CRN })
+
+	snapshot.Sections[target.Name()] = classes
+	snapshot.Timestamp = time.Now()
+}
+
+/* ClassCache Receivers */
+func (cache *ClassCache) ReadSnapshot() (snapshot Snapshot, err error) {
+	snapshot = NewSnapshot()
+
+	blob, err := cache.backend.ReadRecord(SnapshotFilename)
+	if err == ErrNotStored {
+		return snapshot, nil
+	} else if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(blob, &snapshot)
+	if snapshot.Sections == nil {
+		snapshot.Sections = make(map[string][]Class)
+	}
+	return snapshot, err
+}
+
+// WriteSnapshot stores the targets in snapshot, keeping other targets that
+// may be watched by other processes.
+func (cache *ClassCache) WriteSnapshot(snapshot Snapshot) (err error) {
+	log.Println("Writing watch snapshot")
+
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	stored, err := cache.ReadSnapshot()
+	if err != nil {
+		// start over if stored snapshot is unreadable
+		log.Println("Stored snapshot invalid:", err)
+		stored = NewSnapshot()
+	}
+
+	for name, sections := range snapshot.Sections {
+		stored.Sections[name] = sections
+	}
+	stored.Timestamp = snapshot.Timestamp
+
+	blob, err := json.Marshal(stored)
+	if err != nil {
+		return
+	}
+
+	return cache.backend.WriteRecord(SnapshotFilename, blob)
+}
+
+func sectionLabel(class Class) string {
+	return fmt.Sprintf("%s %s (CRN %d)", class.Section, class.Title, class.CRN)
+}
+
+func containsString(strs []string, str string) bool {
+	for _, other := range strs {
+		if other == str {
+			return true
+		}
+	}
+	return false
+}
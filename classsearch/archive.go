@@ -0,0 +1,83 @@
+package classsearch
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+)
+
+var (
+	ErrEmptyArchive = errors.New("Archive does not contain any cache records")
+)
+
+/* Archive Functions */
+func WriteArchive(w io.Writer, backend *MemoryBackend) (err error) {
+	zipper := gzip.NewWriter(w)
+	archive := tar.NewWriter(zipper)
+
+	// write records in stable order
+	names := make([]string, 0, len(backend.Records))
+	for name := range backend.Records {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		blob := backend.Records[name]
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(blob)), ModTime: time.Now()}
+
+		err = archive.WriteHeader(header)
+		if err != nil {
+			return
+		}
+
+		_, err = archive.Write(blob)
+		if err != nil {
+			return
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return
+	}
+
+	return zipper.Close()
+}
+
+func ReadArchive(r io.Reader) (backend *MemoryBackend, err error) {
+	zipper, err := gzip.NewReader(r)
+	if err != nil {
+		return
+	}
+	defer zipper.Close()
+
+	// read each record into memory
+	backend = &MemoryBackend{Records: make(map[string][]byte)}
+	archive := tar.NewReader(zipper)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		blob, err := ioutil.ReadAll(archive)
+		if err != nil {
+			return nil, err
+		}
+
+		backend.Records[header.Name] = blob
+	}
+
+	if len(backend.Records) == 0 {
+		return nil, ErrEmptyArchive
+	}
+
+	return backend, nil
+}
@@ -0,0 +1,183 @@
+package classsearch
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	AnyAttribute = "0ANY"
+)
+
+// AttributeTag is the CRNs found by an attribute-filtered search.
+type AttributeTag struct {
+	Timestamp time.Time
+	CRNs      []int
+}
+
+/* Client Receivers */
+func (client *Client) FetchAttributes(ctx context.Context, opts SearchOptions, codes []string) (tags map[string]AttributeTag, err error) {
+	log.Println("Fetching classes of", len(codes), "attribute(s)")
+
+	// concurrency vars
+	lock := &sync.Mutex{}
+	errChan := make(chan error, len(codes))
+	tags = make(map[string]AttributeTag)
+
+	// search every subject at once for each attribute
+	client.Limits.Workers(len(codes), func(i int) {
+		var input FormInput
+		input.Init(opts, client.Term)
+		input.Attribute = codes[i]
+
+		classes, err := client.Source.Search(ctx, client, input)
+		if err != nil {
+			log.Println("Error with attribute", codes[i])
+			errChan <- err
+			return
+		}
+
+		tag := AttributeTag{Timestamp: time.Now(), CRNs: make([]int, 0, len(classes.List))}
+		for _, class := range classes.List {
+			tag.CRNs = append(tag.CRNs, class.CRN)
+		}
+
+		lock.Lock()
+		tags[codes[i]] = tag
+		lock.Unlock()
+	})
+	close(errChan)
+
+	// fill returned error if available
+	select {
+	case err = <-errChan:
+	default:
+	}
+
+	return tags, err
+}
+
+/* Attribute Functions */
+// TagClasses sets the attributes of classes from tags and returns the
+// subjects of classes that changed.
+func TagClasses(classes *ClassList, tags map[string]AttributeTag) (changed []string) {
+	attributes := make(map[int][]string)
+	for code, tag := range tags {
+		for _, CRN := range tag.CRNs {
+			attributes[CRN] = append(attributes[CRN], code)
+		}
+	}
+
+	found := make(map[string]bool)
+	for _, class := range classes.List {
+		codes := attributes[class.CRN]
+		sort.Strings(codes)
+
+		if !sameStrings(class.Attributes, codes) {
+			class.Attributes = codes
+			classes.Set(class)
+			found[class.GetSubject()] = true
+		}
+	}
+
+	for subject := range found {
+		changed = append(changed, subject)
+	}
+	sort.Strings(changed)
+
+	return changed
+}
+
+func AllAttributes(opts SearchOptions) (codes []string) {
+	for code := range opts.Attributes {
+		if code != AnyAttribute {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+func sameStrings(strs, others []string) bool {
+	if len(strs) != len(others) {
+		return false
+	}
+
+	for i := range strs {
+		if strs[i] != others[i] {
+			return false
+		}
+	}
+	return true
+}
+
+/* ClassCache Receivers */
+func (cache *ClassCache) RefreshAttributes(ctx context.Context, codes []string) (err error) {
+	if cache.Tags == nil {
+		cache.Tags = make(map[string]AttributeTag)
+	}
+
+	// only refetch attributes past class rate
+	stale := make([]string, 0, len(codes))
+	var oldest time.Time
+	for _, code := range codes {
+		tag, ok := cache.Tags[code]
+		if !ok || time.Now().Sub(tag.Timestamp) >= cache.Info.Rate {
+			stale = append(stale, code)
+			if ok && (oldest.IsZero() || tag.Timestamp.Before(oldest)) {
+				oldest = tag.Timestamp
+			}
+		}
+	}
+
+	// serve cached tags when site can't be reached
+	if len(stale) > 0 && cache.offline {
+		cache.warnStale("class attributes", oldest, "offline")
+	} else if len(stale) > 0 {
+		err = cache.fetchAttributes(ctx, stale)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		} else if err == nil {
+			return nil
+		}
+		cache.warnStale("class attributes", oldest, "refresh failed: "+err.Error())
+	}
+
+	// tag newly loaded classes, saved with the next update
+	for _, subject := range TagClasses(&cache.Classes, cache.Tags) {
+		cache.dirty[subject] = true
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) fetchAttributes(ctx context.Context, codes []string) (err error) {
+	log.Println("Performing attribute update")
+
+	// keep other processes from writing during update
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	tags, err := cache.fetcher().FetchAttributes(ctx, cache.OptCache.Options, codes)
+	if err != nil {
+		return
+	}
+
+	for code, tag := range tags {
+		cache.Tags[code] = tag
+	}
+
+	for _, subject := range TagClasses(&cache.Classes, cache.Tags) {
+		cache.dirty[subject] = true
+	}
+
+	// store results
+	return Store(cache, cache.backend)
+}
@@ -0,0 +1,192 @@
+package classsearch
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+const (
+	JSONStore = "json"
+	BoltStore = "bolt"
+)
+
+var (
+	ErrNotStored    = errors.New("Data not found in store")
+	ErrNotIndexed   = errors.New("Store does not support indexed lookups")
+	ErrUnknownStore = errors.New("Unknown store type (use json or bolt)")
+	ErrSameStore    = errors.New("Cannot migrate store to itself")
+	ErrEmptySource  = errors.New("Current store has no cached classes to migrate")
+	ErrTargetInUse  = errors.New("Target store already holds cached data (use --force to overwrite)")
+
+	Stores = []string{JSONStore, BoltStore}
+
+	// every record kept in a store, by cache filename
+	StoreRecords = []string{OptionsCacheFilename, ClassCacheFilename, SnapshotFilename, LedgerFilename}
+)
+
+type Backend interface {
+	// whole records, keyed by cache filename
+	ReadRecord(name string) (blob []byte, err error)
+	WriteRecord(name string, blob []byte) (err error)
+
+	// indexed class storage
+	Indexed() bool
+	ReadClass(CRN int) (class Class, err error)
+	ReadSubject(subject string) (classes []Class, err error)
+	WriteSubjects(subjects []string, classes ClassList) (err error)
+
+	Close() (err error)
+}
+
+type JSONBackend struct {
+	Directory string
+}
+
+// MemoryBackend keeps records in memory, e.g. for archives.
+type MemoryBackend struct {
+	Records map[string][]byte
+}
+
+/* Backend Functions */
+// OpenBackend opens store in dir, waiting up to wait for other processes
+// using it.
+func OpenBackend(store string, dir string, wait time.Duration) (backend Backend, err error) {
+	log.Println("Opening", store, "store in", dir)
+
+	switch store {
+	case JSONStore, "":
+		return &JSONBackend{Directory: dir}, nil
+	case BoltStore:
+		return OpenBoltBackend(dir+BoltFilename, wait)
+	}
+
+	return nil, ErrUnknownStore
+}
+
+// StoreFiles is every file a store keeps in dir, ending with the lock file.
+func StoreFiles(store string, dir string) (files []string) {
+	switch store {
+	case BoltStore:
+		files = append(files, dir+BoltFilename)
+	default:
+		for _, name := range StoreRecords {
+			files = append(files, dir+name)
+		}
+	}
+
+	return append(files, dir+LockFilename)
+}
+
+// StoreEmpty tells whether backend holds none of the store records.
+func StoreEmpty(backend Backend) (empty bool, err error) {
+	for _, name := range StoreRecords {
+		_, err = backend.ReadRecord(name)
+		if err == nil {
+			return false, nil
+		} else if err != ErrNotStored {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// copyRecords copies records other than options and classes, which are
+// written through Store.
+func copyRecords(from Backend, to Backend) (err error) {
+	for _, name := range StoreRecords {
+		if name == OptionsCacheFilename || name == ClassCacheFilename {
+			continue
+		}
+
+		blob, err := from.ReadRecord(name)
+		if err == ErrNotStored {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		err = to.WriteRecord(name, blob)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+/* JSONBackend Receivers */
+func (backend *JSONBackend) ReadRecord(name string) (blob []byte, err error) {
+	blob, err = ioutil.ReadFile(backend.Directory + name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotStored
+	}
+
+	return blob, err
+}
+
+func (backend *JSONBackend) WriteRecord(name string, blob []byte) (err error) {
+	return writeFileAtomic(backend.Directory+name, blob, CacheFileMode)
+}
+
+func (backend *JSONBackend) Indexed() bool {
+	return false
+}
+
+func (backend *JSONBackend) ReadClass(CRN int) (class Class, err error) {
+	return class, ErrNotIndexed
+}
+
+func (backend *JSONBackend) ReadSubject(subject string) (classes []Class, err error) {
+	return nil, ErrNotIndexed
+}
+
+func (backend *JSONBackend) WriteSubjects(subjects []string, classes ClassList) (err error) {
+	return ErrNotIndexed
+}
+
+func (backend *JSONBackend) Close() (err error) {
+	return nil
+}
+
+/* MemoryBackend Receivers */
+func (backend *MemoryBackend) ReadRecord(name string) (blob []byte, err error) {
+	blob, ok := backend.Records[name]
+	if !ok {
+		return nil, ErrNotStored
+	}
+
+	return blob, nil
+}
+
+func (backend *MemoryBackend) WriteRecord(name string, blob []byte) (err error) {
+	if backend.Records == nil {
+		backend.Records = make(map[string][]byte)
+	}
+
+	backend.Records[name] = blob
+	return nil
+}
+
+func (backend *MemoryBackend) Indexed() bool {
+	return false
+}
+
+func (backend *MemoryBackend) ReadClass(CRN int) (class Class, err error) {
+	return class, ErrNotIndexed
+}
+
+func (backend *MemoryBackend) ReadSubject(subject string) (classes []Class, err error) {
+	return nil, ErrNotIndexed
+}
+
+func (backend *MemoryBackend) WriteSubjects(subjects []string, classes ClassList) (err error) {
+	return ErrNotIndexed
+}
+
+func (backend *MemoryBackend) Close() (err error) {
+	return nil
+}
@@ -0,0 +1,435 @@
+package classsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	bannerPath     = "/StudentRegistrationSsb/ssb"
+	bannerPageSize = 500
+	bannerCookies  = "banner.cookies"
+)
+
+var (
+	ErrBannerSite   = errors.New("Banner source needs --site set to the school's Banner server")
+	ErrBannerSearch = errors.New("Banner search was not successful")
+
+	// Banner day flags in class time order
+	bannerDays = []struct {
+		Field  string
+		Letter string
+	}{
+		{"monday", "M"}, {"tuesday", "T"}, {"wednesday", "W"}, {"thursday", "R"},
+		{"friday", "F"}, {"saturday", "S"}, {"sunday", "U"},
+	}
+)
+
+// BannerSource uses the JSON endpoints of Banner 9 Student Registration.
+// Banner keeps search state in the session, so searches run one at a time.
+type BannerSource struct {
+	Site    string
+	Cookies string // curl cookie jar holding the session
+
+	lock     sync.Mutex
+	term     string            // term of current session
+	subjects map[string]string // subject descriptions to codes
+}
+
+type bannerCode struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+type bannerResults struct {
+	Success    bool            `json:"success"`
+	TotalCount int             `json:"totalCount"`
+	Data       []bannerSection `json:"data"`
+}
+
+type bannerSection struct {
+	CRN               string           `json:"courseReferenceNumber"`
+	Subject           string           `json:"subject"`
+	CourseNumber      string           `json:"courseNumber"`
+	SequenceNumber    string           `json:"sequenceNumber"`
+	CourseTitle       string           `json:"courseTitle"`
+	CreditHours       *float64         `json:"creditHours"`
+	CreditHourLow     *float64         `json:"creditHourLow"`
+	CreditHourHigh    *float64         `json:"creditHourHigh"`
+	MaximumEnrollment int              `json:"maximumEnrollment"`
+	SeatsAvailable    int              `json:"seatsAvailable"`
+	Faculty           []bannerFaculty  `json:"faculty"`
+	MeetingsFaculty   []bannerMeetings `json:"meetingsFaculty"`
+}
+
+type bannerFaculty struct {
+	DisplayName      string `json:"displayName"`
+	PrimaryIndicator bool   `json:"primaryIndicator"`
+}
+
+type bannerMeetings struct {
+	MeetingTime map[string]interface{} `json:"meetingTime"`
+}
+
+/* BannerSource Functions */
+// NewBannerSource keeps its session in cache directory dir, or the default
+// one if empty.
+func NewBannerSource(site string, dir string) (source *BannerSource) {
+	if dir == "" {
+		dir = DefaultDirectory()
+	}
+
+	// session only needs to outlive one run
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		dir = os.TempDir()
+	}
+
+	return &BannerSource{
+		Site:    strings.TrimRight(site, "/"),
+		Cookies: filepath.Join(dir, bannerCookies),
+	}
+}
+
+/* BannerSource Receivers */
+func (source *BannerSource) Options(ctx context.Context, fetcher Fetcher, term string) (opts SearchOptions, err error) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	terms, err := source.codes(ctx, fetcher, "classSearch/getTerms", url.Values{})
+	if err != nil {
+		return
+	}
+	opts.Terms = codeMap(terms)
+	term = opts.Term(term)
+
+	// remaining options depend on term
+	lists := []struct {
+		endpoint string
+		field    *map[string]string
+	}{
+		{"classSearch/get_subject", &opts.Subjects},
+		{"classSearch/get_campus", &opts.Campuses},
+		{"classSearch/get_attribute", &opts.Attributes},
+	}
+
+	for _, list := range lists {
+		codes, err := source.codes(ctx, fetcher, list.endpoint, url.Values{"term": {term}})
+		if err != nil {
+			return opts, err
+		}
+		*list.field = codeMap(codes)
+	}
+
+	// remember subject names used in prerequisites
+	source.subjects = make(map[string]string)
+	for code, description := range opts.Subjects {
+		source.subjects[description] = code
+	}
+
+	return opts, nil
+}
+
+func (source *BannerSource) Search(ctx context.Context, fetcher Fetcher, input FormInput) (classes ClassList, err error) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	classes.Init()
+	err = source.startSession(ctx, fetcher, input.Term)
+	if err != nil {
+		return
+	}
+
+	for _, subject := range input.Subjects {
+		// clear previous search from session
+		_, err = source.post(ctx, fetcher, "classSearch/resetDataForm", url.Values{})
+		if err != nil {
+			return
+		}
+
+		// get every page of results
+		for offset := 0; ; offset += bannerPageSize {
+			query := url.Values{
+				"txt_subject":   {subject},
+				"txt_term":      {input.Term},
+				"pageOffset":    {strconv.Itoa(offset)},
+				"pageMaxSize":   {strconv.Itoa(bannerPageSize)},
+				"sortColumn":    {"subjectDescription"},
+				"sortDirection": {"asc"},
+			}
+			if input.Attribute != "" && input.Attribute != AnyAttribute {
+				query.Set("txt_attribute", input.Attribute)
+			}
+
+			var results bannerResults
+			err = source.getJSON(ctx, fetcher, "searchResults/searchResults", query, &results)
+			if err != nil {
+				return
+			}
+
+			if !results.Success {
+				return classes, ErrBannerSearch
+			}
+
+			for _, section := range results.Data {
+				class, err := section.Class()
+				if err != nil {
+					return classes, err
+				}
+				classes.Add(class)
+			}
+
+			if len(results.Data) == 0 || offset+bannerPageSize >= results.TotalCount {
+				break
+			}
+		}
+	}
+
+	return classes, nil
+}
+
+func (source *BannerSource) Section(ctx context.Context, fetcher Fetcher, term string, CRN int) (detail SectionDetail, err error) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	query := url.Values{"term": {term}, "courseReferenceNumber": {strconv.Itoa(CRN)}}
+
+	// both are html fragments
+	page, err := source.get(ctx, fetcher, "searchResults/getSectionPrerequisites", query)
+	if err != nil {
+		return
+	}
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return
+	}
+	detail.Prerequisites = source.prerequisites(doc)
+
+	page, err = source.get(ctx, fetcher, "searchResults/getRestrictions", query)
+	if err != nil {
+		return
+	}
+
+	doc, err = html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return
+	}
+	detail.Restrictions = splitRestrictions(nodeText(doc))
+
+	return detail, nil
+}
+
+func (source *BannerSource) startSession(ctx context.Context, fetcher Fetcher, term string) (err error) {
+	if source.term == term {
+		return nil
+	}
+
+	log.Println("Starting Banner session for term", term)
+	_, err = source.post(ctx, fetcher, "term/search?mode=search", url.Values{"term": {term}})
+	if err != nil {
+		return
+	}
+
+	source.term = term
+	return nil
+}
+
+func (source *BannerSource) prerequisites(doc *html.Node) string {
+	// rows are: and/or, (, test, score, subject, course number, level, grade, )
+	parts := make([]string, 0, 10)
+	for _, row := range findAll(doc, "tr") {
+		cells := findAll(row, "td")
+		if len(cells) < 9 {
+			continue
+		}
+
+		subject := nodeText(cells[4])
+		if code, ok := source.subjects[subject]; ok {
+			subject = code
+		}
+
+		course := strings.TrimSpace(subject + " " + nodeText(cells[5]))
+		if test := nodeText(cells[2]); test != "" {
+			course = test + " " + nodeText(cells[3])
+		} else if grade := nodeText(cells[7]); grade != "" {
+			course += " minimum grade of " + grade
+		}
+
+		for _, part := range []string{strings.ToLower(nodeText(cells[0])), nodeText(cells[1]), course, nodeText(cells[8])} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+	}
+
+	// fall back to page text if there is no table
+	if len(parts) == 0 {
+		text := nodeText(doc)
+		if strings.Contains(strings.ToLower(text), "no prerequisite") {
+			return ""
+		}
+		return text
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (source *BannerSource) codes(ctx context.Context, fetcher Fetcher, endpoint string, query url.Values) (codes []bannerCode, err error) {
+	query.Set("searchTerm", "")
+	query.Set("offset", "1")
+	query.Set("max", "1000")
+
+	err = source.getJSON(ctx, fetcher, endpoint, query, &codes)
+	return codes, err
+}
+
+func (source *BannerSource) getJSON(ctx context.Context, fetcher Fetcher, endpoint string, query url.Values, value interface{}) (err error) {
+	page, err := source.get(ctx, fetcher, endpoint, query)
+	if err != nil {
+		return
+	}
+
+	return json.Unmarshal(page, value)
+}
+
+func (source *BannerSource) get(ctx context.Context, fetcher Fetcher, endpoint string, query url.Values) (page []byte, err error) {
+	return fetcher.Fetch(ctx, source.Site+bannerPath+"/"+endpoint+"?"+query.Encode(), source.cookieArgs())
+}
+
+func (source *BannerSource) post(ctx context.Context, fetcher Fetcher, endpoint string, form url.Values) (page []byte, err error) {
+	return fetcher.Fetch(ctx, source.Site+bannerPath+"/"+endpoint, append(source.cookieArgs(), "--data", form.Encode()))
+}
+
+func (source *BannerSource) cookieArgs() []string {
+	return []string{"--cookie", source.Cookies, "--cookie-jar", source.Cookies}
+}
+
+/* bannerSection Receivers */
+func (section bannerSection) Class() (class Class, err error) {
+	class.CRN, err = strconv.Atoi(section.CRN)
+	if err != nil {
+		return
+	}
+
+	class.Section = fmt.Sprintf("%s %s - %s", section.Subject, section.CourseNumber, section.SequenceNumber)
+	class.Title = html.UnescapeString(section.CourseTitle)
+	class.Max = section.MaximumEnrollment
+	class.Open = section.SeatsAvailable
+
+	// credits may be a range
+	switch {
+	case section.CreditHours != nil:
+		class.Credits = strconv.FormatFloat(*section.CreditHours, 'f', -1, 64)
+	case section.CreditHourLow != nil && section.CreditHourHigh != nil:
+		class.Credits = strconv.FormatFloat(*section.CreditHourLow, 'f', -1, 64) + "-" + strconv.FormatFloat(*section.CreditHourHigh, 'f', -1, 64)
+	case section.CreditHourLow != nil:
+		class.Credits = strconv.FormatFloat(*section.CreditHourLow, 'f', -1, 64)
+	}
+
+	// list primary instructor first
+	names := make([]string, 0, len(section.Faculty))
+	for _, faculty := range section.Faculty {
+		if faculty.PrimaryIndicator {
+			names = append([]string{faculty.DisplayName}, names...)
+		} else {
+			names = append(names, faculty.DisplayName)
+		}
+	}
+	class.Instructor = strings.Join(names, "; ")
+	if class.Instructor == "" {
+		class.Instructor = UnknownInstructor
+	}
+
+	// write meetings in the same format as the ND site
+	times := make([]string, 0, len(section.MeetingsFaculty))
+	rooms := make([]string, 0, len(section.MeetingsFaculty))
+	for _, meeting := range section.MeetingsFaculty {
+		fields := meeting.MeetingTime
+		if fields == nil {
+			continue
+		}
+
+		if when := bannerTime(fields); when != "" {
+			times = append(times, when)
+		}
+
+		if building, _ := fields["buildingDescription"].(string); building != "" {
+			room, _ := fields["room"].(string)
+			rooms = append(rooms, strings.TrimSpace(building+" "+room))
+		}
+
+		if class.Begin == "" {
+			class.Begin, _ = fields["startDate"].(string)
+			class.End, _ = fields["endDate"].(string)
+		}
+	}
+
+	class.Time = strings.Join(times, ", ")
+	if class.Time == "" {
+		class.Time = "TBA"
+	}
+
+	class.Location = strings.Join(rooms, ", ")
+	if class.Location == "" {
+		class.Location = "TBA"
+	}
+
+	return class, nil
+}
+
+/* Banner Helpers */
+func bannerTime(fields map[string]interface{}) string {
+	days := ""
+	for _, day := range bannerDays {
+		if meets, _ := fields[day.Field].(bool); meets {
+			days += day.Letter
+		}
+	}
+
+	// times are given as "0930"
+	begin, _ := fields["beginTime"].(string)
+	end, _ := fields["endTime"].(string)
+	start, startErr := strconv.Atoi(begin)
+	finish, finishErr := strconv.Atoi(end)
+	if days == "" || startErr != nil || finishErr != nil {
+		return ""
+	}
+
+	return days + " - " + FormatClock(start/100*60+start%100) + " - " + FormatClock(finish/100*60+finish%100)
+}
+
+func codeMap(codes []bannerCode) (mapping map[string]string) {
+	mapping = make(map[string]string)
+	for _, code := range codes {
+		mapping[code.Code] = html.UnescapeString(code.Description)
+	}
+	return mapping
+}
+
+func findAll(node *html.Node, tag string) (found []*html.Node) {
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == tag {
+			found = append(found, node)
+			return
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return found
+}
@@ -0,0 +1,228 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"go.etcd.io/bbolt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	BoltFilename = "cscli.db"
+)
+
+var (
+	recordsBucket  = []byte("records")
+	classesBucket  = []byte("classes")  // CRN -> class
+	subjectsBucket = []byte("subjects") // subject -> CRNs
+)
+
+// BoltBackend opens its database for each read or write, so other cscli
+// processes are only locked out while one is in progress.
+type BoltBackend struct {
+	Filename string
+	Wait     time.Duration // how long to wait for other processes
+}
+
+/* BoltBackend Functions */
+func OpenBoltBackend(filename string, wait time.Duration) (backend *BoltBackend, err error) {
+	return &BoltBackend{Filename: filename, Wait: wait}, nil
+}
+
+/* BoltBackend Receivers */
+func (backend *BoltBackend) open(readOnly bool) (db *bbolt.DB, err error) {
+	// bolt waits forever without a timeout, try once instead
+	timeout := backend.Wait
+	if timeout <= 0 {
+		timeout = time.Nanosecond
+	}
+
+	// readers share the database, writers wait for everyone else
+	db, err = bbolt.Open(backend.Filename, CacheFileMode, &bbolt.Options{ReadOnly: readOnly, Timeout: timeout})
+	if err == bbolt.ErrTimeout {
+		return nil, ErrCacheLocked
+	}
+	return db, err
+}
+
+func (backend *BoltBackend) view(fn func(tx *bbolt.Tx) error) (err error) {
+	// bolt creates missing files even when reading
+	if _, err = os.Stat(backend.Filename); os.IsNotExist(err) {
+		return ErrNotStored
+	}
+
+	db, err := backend.open(true)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	return db.View(func(tx *bbolt.Tx) error {
+		if tx.Bucket(recordsBucket) == nil {
+			return ErrNotStored
+		}
+		return fn(tx)
+	})
+}
+
+func (backend *BoltBackend) update(fn func(tx *bbolt.Tx) error) (err error) {
+	db, err := backend.open(false)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	return db.Update(func(tx *bbolt.Tx) error {
+		// make sure buckets exist
+		for _, bucket := range [][]byte{recordsBucket, classesBucket, subjectsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return fn(tx)
+	})
+}
+
+func (backend *BoltBackend) ReadRecord(name string) (blob []byte, err error) {
+	err = backend.view(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(recordsBucket).Get([]byte(name))
+		if value == nil {
+			return ErrNotStored
+		}
+
+		// values are only valid during transaction
+		blob = append([]byte{}, value...)
+		return nil
+	})
+
+	return blob, err
+}
+
+func (backend *BoltBackend) WriteRecord(name string, blob []byte) (err error) {
+	return backend.update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(recordsBucket).Put([]byte(name), blob)
+	})
+}
+
+func (backend *BoltBackend) Indexed() bool {
+	return true
+}
+
+func (backend *BoltBackend) ReadClass(CRN int) (class Class, err error) {
+	err = backend.view(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(classesBucket).Get([]byte(strconv.Itoa(CRN)))
+		if value == nil {
+			return ErrNotStored
+		}
+
+		return json.Unmarshal(value, &class)
+	})
+
+	return class, err
+}
+
+func (backend *BoltBackend) ReadSubject(subject string) (classes []Class, err error) {
+	err = backend.view(func(tx *bbolt.Tx) error {
+		// get CRNs in subject
+		var CRNs []int
+		value := tx.Bucket(subjectsBucket).Get([]byte(subject))
+		if value == nil {
+			return nil
+		}
+
+		err := json.Unmarshal(value, &CRNs)
+		if err != nil {
+			return err
+		}
+
+		// get each class
+		classes = make([]Class, 0, len(CRNs))
+		for _, CRN := range CRNs {
+			var class Class
+			value := tx.Bucket(classesBucket).Get([]byte(strconv.Itoa(CRN)))
+			if value == nil {
+				continue
+			}
+
+			err = json.Unmarshal(value, &class)
+			if err != nil {
+				return err
+			}
+
+			classes = append(classes, class)
+		}
+
+		return nil
+	})
+
+	// nothing stored yet
+	if err == ErrNotStored {
+		return nil, nil
+	}
+	return classes, err
+}
+
+func (backend *BoltBackend) WriteSubjects(subjects []string, classes ClassList) (err error) {
+	// group classes by subject
+	grouped := make(map[string][]Class)
+	for _, subject := range subjects {
+		grouped[subject] = make([]Class, 0, 20)
+	}
+
+	for _, class := range classes.List {
+		if group, ok := grouped[class.GetSubject()]; ok {
+			grouped[class.GetSubject()] = append(group, class)
+		}
+	}
+
+	// replace subjects in one transaction
+	return backend.update(func(tx *bbolt.Tx) error {
+		classBucket := tx.Bucket(classesBucket)
+		subjectBucket := tx.Bucket(subjectsBucket)
+
+		for subject, group := range grouped {
+			// remove old classes of subject
+			var oldCRNs []int
+			if value := subjectBucket.Get([]byte(subject)); value != nil {
+				json.Unmarshal(value, &oldCRNs)
+			}
+
+			for _, CRN := range oldCRNs {
+				if err := classBucket.Delete([]byte(strconv.Itoa(CRN))); err != nil {
+					return err
+				}
+			}
+
+			// add new classes
+			CRNs := make([]int, 0, len(group))
+			for _, class := range group {
+				blob, err := json.Marshal(class)
+				if err != nil {
+					return err
+				}
+
+				if err = classBucket.Put([]byte(strconv.Itoa(class.CRN)), blob); err != nil {
+					return err
+				}
+				CRNs = append(CRNs, class.CRN)
+			}
+
+			blob, err := json.Marshal(CRNs)
+			if err != nil {
+				return err
+			}
+
+			if err = subjectBucket.Put([]byte(subject), blob); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (backend *BoltBackend) Close() (err error) {
+	// database is closed after every read and write
+	return nil
+}
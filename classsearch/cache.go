@@ -0,0 +1,998 @@
+package classsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+/* FIXME: remove these after testing */
+var _ = fmt.Printf
+
+const (
+	DefaultClassRate   = time.Hour * 24
+	DefaultOptionsRate = time.Hour * 24 * 7
+
+	ClassCacheFilename   = "class_cache.json"
+	OptionsCacheFilename = "options_cache.json"
+
+	// cache files are private to the current user
+	CacheFileMode = 0600
+)
+
+// CacheNeed is how much of the cache a command uses.
+type CacheNeed int
+
+const (
+	NeedNothing CacheNeed = iota
+	NeedOptions
+	NeedClasses // classes are still loaded per subject when used
+)
+
+var (
+	ErrNoCache          = errors.New("Cache was not initialied")
+	ErrInvalidDirectory = errors.New("Invalid directory specified")
+	ErrOfflineNoCache   = errors.New("No cached data available in offline mode")
+	ErrOfflineRefresh   = errors.New("Cannot refresh cache in offline mode")
+)
+
+type Cache interface {
+	// cache-specific info
+	GetInfo() (timer CacheInfo)
+	IsStale() (stale bool)
+
+	// storage
+	Save(backend Backend) (err error)
+	Load(backend Backend) (err error)
+
+	// api
+	FetchData(ctx context.Context, client *Client) (err error)
+}
+
+// StaleWarning is cached data served in place of data that couldn't be
+// fetched.
+type StaleWarning struct {
+	What      string
+	Timestamp time.Time // zero if unknown
+	Reason    string
+}
+
+type CacheInfo struct {
+	Timestamp time.Time
+	Rate      time.Duration
+	Filename  string
+	Directory string
+}
+
+type ClassCache struct {
+	Version  int
+	Info     CacheInfo
+	Classes  ClassList
+	Subjects map[string]SubjectInfo // staleness of each subject in Classes
+	OptCache *OptionsCache
+	Tags     map[string]AttributeTag `json:",omitempty"` // CRNs of each fetched attribute
+
+	lock     *FileLock
+	client   *Client // fetches data, set by NewClient
+	offline  bool    // never fetch, only serve stored data
+	mode     FetchMode
+	store    string
+	backend  Backend
+	loaded   map[string]bool // subjects with classes in memory
+	restored CacheNeed
+	dirty    map[string]bool // subjects changed since last save
+}
+
+type SubjectInfo struct {
+	Timestamp time.Time
+	Rate      time.Duration
+	Stale     bool // refetch regardless of age, e.g. after a migration
+}
+
+type OptionsCache struct {
+	Version int
+	Info    CacheInfo
+	Options SearchOptions
+}
+
+/* CacheInfo Functions */
+func (info *CacheInfo) Init(filename string, rate time.Duration) {
+	info.Timestamp = time.Now()
+	info.Rate = rate
+	info.Filename = filename
+	info.Directory = DefaultDirectory()
+}
+
+func DefaultDirectory() string {
+	// per-user cache dir, e.g. $XDG_CACHE_HOME/cscli/
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "cscli") + string(filepath.Separator)
+}
+
+func (info CacheInfo) IsStale() bool {
+	return time.Now().Sub(info.Timestamp) >= info.Rate
+}
+
+func (info CacheInfo) Filepath() string {
+	return info.Directory + info.Filename
+}
+
+/* SubjectInfo Functions */
+func (info SubjectInfo) IsStale() bool {
+	return info.Stale || time.Now().Sub(info.Timestamp) >= info.Rate
+}
+
+/* ClassCache Functions */
+func (cache *ClassCache) Init() {
+	// init fields
+	cache.Version = CacheVersion
+	cache.Info.Init(ClassCacheFilename, DefaultClassRate)
+	cache.Subjects = make(map[string]SubjectInfo)
+	cache.OptCache = &OptionsCache{}
+	cache.OptCache.Init()
+	cache.lock = &FileLock{Path: cache.Info.Directory + LockFilename, Wait: DefaultLockWait}
+	cache.loaded = make(map[string]bool)
+	cache.dirty = make(map[string]bool)
+}
+
+func (cache *ClassCache) Open(store string) (err error) {
+	backend, err := OpenBackend(store, cache.Info.Directory, cache.lock.Wait)
+	if err != nil {
+		return
+	}
+
+	cache.Close()
+	cache.store = store
+	cache.backend = backend
+	return nil
+}
+
+func (cache *ClassCache) StoreType() string {
+	return cache.store
+}
+
+func (cache *ClassCache) Files() (files []string) {
+	return StoreFiles(cache.store, cache.Info.Directory)
+}
+
+func (cache *ClassCache) Clear() (err error) {
+	log.Println("Clearing cache files")
+
+	// keep other processes from reading files during clear
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	// release store so files can be removed
+	err = cache.Close()
+	if err != nil {
+		return
+	}
+
+	for _, file := range cache.Files() {
+		// lock file stays so other processes keep locking the same file
+		if file == cache.lock.Path {
+			continue
+		}
+
+		err = os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// reset in-memory data, keeping settings, and reopen empty store
+	cache.reset()
+	return cache.Open(cache.store)
+}
+
+// reset drops all cached data but keeps settings like the directory, rates,
+// store and offline mode.
+func (cache *ClassCache) reset() {
+	cache.Version = CacheVersion
+	cache.Info.Timestamp = time.Now()
+	cache.Classes.Init()
+	cache.Subjects = make(map[string]SubjectInfo)
+	cache.Tags = nil
+	cache.OptCache.Version = CacheVersion
+	cache.OptCache.Info.Timestamp = time.Now()
+	cache.OptCache.Options = SearchOptions{}
+	cache.loaded = make(map[string]bool)
+	cache.dirty = make(map[string]bool)
+	cache.restored = NeedNothing
+}
+
+// ReadStored reads stored data without refreshing it.
+func (cache *ClassCache) ReadStored() (err error) {
+	err = cache.OptCache.Load(cache.backend)
+	if err != nil && err != ErrNotStored {
+		return
+	}
+
+	err = cache.Load(cache.backend)
+	if err != nil && err != ErrNotStored {
+		return
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) Import(backend Backend) (err error) {
+	log.Println("Importing cache data")
+
+	// keep other processes from reading files during import
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	// read imported records, upgrading old versions
+	err = cache.OptCache.Load(backend)
+	if err != nil {
+		return
+	}
+
+	err = cache.Load(backend)
+	if err != nil {
+		return
+	}
+
+	// write everything to current store
+	for subject := range cache.Subjects {
+		cache.loaded[subject] = true
+		cache.dirty[subject] = true
+	}
+
+	err = Store(cache.OptCache, cache.backend)
+	if err != nil {
+		return
+	}
+
+	err = Store(cache, cache.backend)
+	if err != nil {
+		return
+	}
+
+	// watch snapshots and the notification ledger
+	return copyRecords(backend, cache.backend)
+}
+
+func (cache *ClassCache) Close() (err error) {
+	if cache.backend == nil {
+		return nil
+	}
+	return cache.backend.Close()
+}
+
+func (cache *ClassCache) Lock() (err error) {
+	return cache.lock.Lock()
+}
+
+func (cache *ClassCache) Unlock() (err error) {
+	return cache.lock.Unlock()
+}
+
+func (cache *ClassCache) SetLockWait(wait time.Duration) {
+	cache.lock.Wait = wait
+}
+
+func (cache *ClassCache) SetOffline(offline bool) {
+	cache.offline = offline
+}
+
+func (cache *ClassCache) SetFetchMode(mode FetchMode) {
+	cache.mode = mode
+}
+
+func (cache *ClassCache) SetRates(classRate, optionsRate time.Duration) {
+	cache.Info.Rate = classRate
+	cache.OptCache.Info.Rate = optionsRate
+}
+
+func (cache *ClassCache) MakeDirectory() (err error) {
+	// only the current user can read the cache
+	return os.MkdirAll(cache.Info.Directory, 0700)
+}
+
+func (cache *ClassCache) Require(ctx context.Context, need CacheNeed) (err error) {
+	// only restore once
+	if need <= cache.restored {
+		return nil
+	}
+
+	log.Println("Reading/Restoring cache from store")
+
+	// keep other processes from writing during restore
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	err = Restore(ctx, cache.fetcher(), cache.OptCache, cache.backend, cache.offline)
+	if err != nil {
+		return
+	}
+
+	if need == NeedClasses {
+		err = cache.restoreClasses()
+		if err != nil {
+			return
+		}
+	}
+
+	cache.restored = need
+	log.Println("Restoration complete")
+	return nil
+}
+
+func (cache *ClassCache) Refresh(ctx context.Context) (err error) {
+	log.Println("Refreshing all cache data")
+
+	if cache.offline {
+		return ErrOfflineRefresh
+	}
+
+	// keep other processes from writing during refresh
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	// keep stored classes of subjects that fail to fetch
+	err = cache.restoreClasses()
+	if err != nil {
+		return
+	}
+
+	// fetch everything before touching stored data
+	err = cache.OptCache.FetchData(ctx, cache.fetcher())
+	if err != nil {
+		return
+	}
+
+	err = cache.FetchData(ctx, cache.fetcher())
+	if err != nil {
+		return
+	}
+
+	err = Store(cache.OptCache, cache.backend)
+	if err != nil {
+		return
+	}
+
+	return Store(cache, cache.backend)
+}
+
+func (cache *ClassCache) restoreClasses() (err error) {
+	// read class metadata, subjects are fetched when first used
+	err = cache.Load(cache.backend)
+	switch err {
+	case nil, ErrNotStored:
+		return nil
+	case ErrCacheTooNew:
+		return err
+	}
+
+	// start over if stored data is unreadable
+	log.Println("Stored cache data invalid:", err)
+	cache.Classes.Init()
+	cache.Subjects = make(map[string]SubjectInfo)
+	cache.Tags = nil
+	cache.loaded = make(map[string]bool)
+	return nil
+}
+
+func (cache *ClassCache) SetDirectory(dir string) (err error) {
+	// check that directory file exists
+	file, err := os.Open(dir)
+	defer file.Close()
+	if err != nil {
+		return
+	}
+
+	// check that file is directory
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	if !info.IsDir() {
+		return ErrInvalidDirectory
+	}
+
+	// set cache directories
+	if !(dir[len(dir)-1] == '/') {
+		dir += "/"
+	}
+
+	cache.OptCache.Info.Directory = dir
+	cache.Info.Directory = dir
+	cache.lock.Path = dir + LockFilename
+
+	return nil
+}
+
+func (cache *ClassCache) FetchUpdates(ctx context.Context, CRNs []int) (err error) {
+	log.Println("Performing data update")
+	subjects := make([]string, 0, 10)
+	found := make(map[string]bool)
+
+	// get subjects, once each
+	for _, CRN := range CRNs {
+		class, ok := cache.Class(CRN)
+
+		// check to make sure CRN is valid
+		if !ok {
+			return &CRNError{CRN: CRN, Err: ErrNoClass}
+		}
+
+		if subject := class.GetSubject(); !found[subject] {
+			found[subject] = true
+			subjects = append(subjects, subject)
+		}
+	}
+
+	return cache.UpdateSubjects(ctx, subjects)
+}
+
+// UpdateSubjects refetches subjects regardless of staleness. Unlike
+// RefreshSubjects, failures are returned since fresh data was asked for.
+func (cache *ClassCache) UpdateSubjects(ctx context.Context, subjects []string) (err error) {
+	// serve cached data when told not to fetch
+	if cache.offline {
+		cache.warnSubjects(subjects, "offline")
+		return nil
+	}
+
+	err = cache.fetchSubjects(ctx, subjects)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	} else if err != nil {
+		return
+	}
+
+	log.Println("Update complete")
+	return nil
+}
+
+func (cache *ClassCache) RefreshSubjects(ctx context.Context, subjects []string) (err error) {
+	stale := make([]string, 0, len(subjects))
+
+	// only refetch subjects past their rate
+	for _, subject := range subjects {
+		if cache.subjectInfo(subject).IsStale() {
+			stale = append(stale, subject)
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+
+	// serve cached data when site can't be reached
+	if cache.offline {
+		cache.warnSubjects(stale, "offline")
+		return nil
+	}
+
+	log.Println("Refreshing", len(stale), "stale subject(s)")
+	err = cache.fetchSubjects(ctx, stale)
+	if err != nil {
+		// fall back to stale data if there is any
+		if ctx.Err() != nil || !cache.hasSubjects(stale) {
+			return err
+		}
+
+		cache.warnSubjects(stale, "refresh failed: "+err.Error())
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) hasSubjects(subjects []string) bool {
+	for _, subject := range subjects {
+		if !cache.Subjects[subject].Timestamp.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+func (cache *ClassCache) warnSubjects(subjects []string, reason string) {
+	// report oldest data being served
+	var oldest time.Time
+	for _, subject := range subjects {
+		timestamp := cache.Subjects[subject].Timestamp
+		if !timestamp.IsZero() && (oldest.IsZero() || timestamp.Before(oldest)) {
+			oldest = timestamp
+		}
+	}
+
+	what := fmt.Sprintf("classes for %d subject(s)", len(subjects))
+	if len(subjects) <= 5 {
+		what = "classes for " + strings.Join(subjects, ", ")
+	}
+
+	cache.warnStale(what, oldest, reason)
+}
+
+func (cache *ClassCache) warnStale(what string, timestamp time.Time, reason string) {
+	cache.fetcher().warn(&StaleWarning{What: what, Timestamp: timestamp, Reason: reason})
+}
+
+// fetcher is the client fetching for cache, with default settings if the
+// cache isn't used through one.
+func (cache *ClassCache) fetcher() *Client {
+	if cache.client == nil {
+		cache.client = NewClient(cache)
+	}
+	return cache.client
+}
+
+func (cache *ClassCache) SubjectsFor(info FilterInfo) (subjects []string) {
+	found := make(map[string]bool)
+
+	// subjects of requested CRNs
+	known := len(info.CRNs) > 0
+	for _, CRN := range info.CRNs {
+		class, ok := cache.Class(CRN)
+		if !ok {
+			known = false
+			break
+		}
+
+		found[class.GetSubject()] = true
+	}
+
+	// otherwise subjects matching requested departments
+	if !known {
+		return subjectsMatching(cache.OptCache.Options, info.Departments)
+	}
+
+	subjects = make([]string, 0, len(found))
+	for subject := range found {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	return subjects
+}
+
+func (cache *ClassCache) Class(CRN int) (class Class, ok bool) {
+	class, ok = cache.Classes.Map[CRN]
+	if ok || !cache.backend.Indexed() {
+		return
+	}
+
+	// look up class without loading everything
+	class, err := cache.backend.ReadClass(CRN)
+	if err != nil {
+		return class, false
+	}
+
+	// load rest of subject so it stays consistent in memory
+	err = cache.LoadSubjects([]string{class.GetSubject()})
+	return class, err == nil
+}
+
+func (cache *ClassCache) LoadSubjects(subjects []string) (err error) {
+	if !cache.backend.Indexed() {
+		return nil
+	}
+
+	for _, subject := range subjects {
+		if cache.loaded[subject] {
+			continue
+		}
+
+		classes, err := cache.backend.ReadSubject(subject)
+		if err != nil {
+			return err
+		}
+
+		for _, class := range classes {
+			cache.Classes.Add(class)
+		}
+		cache.loaded[subject] = true
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) CopyTo(backend Backend) (err error) {
+	log.Println("Copying cache to new store")
+
+	// load every subject into memory
+	subjects := make([]string, 0, len(cache.Subjects))
+	for subject := range cache.Subjects {
+		subjects = append(subjects, subject)
+	}
+
+	err = cache.LoadSubjects(subjects)
+	if err != nil {
+		return
+	}
+
+	// save everything to new store
+	for _, subject := range subjects {
+		cache.dirty[subject] = true
+	}
+
+	err = Store(cache.OptCache, backend)
+	if err != nil {
+		return
+	}
+
+	err = Store(cache, backend)
+	if err != nil {
+		return
+	}
+
+	// watch snapshots and the notification ledger
+	return copyRecords(cache.backend, backend)
+}
+
+func (cache *ClassCache) subjectInfo(subject string) (info SubjectInfo) {
+	// subjects never fetched are always stale
+	info = cache.Subjects[subject]
+	info.Rate = cache.Info.Rate
+	return info
+}
+
+func (cache *ClassCache) fetchSubjects(ctx context.Context, subjects []string) (err error) {
+	// keep other processes from writing during update
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	// create form
+	client := cache.fetcher()
+	var input FormInput
+	input.Init(cache.OptCache.Options, client.Term)
+	input.Subjects = subjects
+
+	// retrieve updated classes from data fetch
+	updates, err := client.ParseParallel(ctx, input, cache.mode)
+	subjects, err = client.partialResult(cache.mode, subjects, err)
+	if err != nil {
+		return
+	}
+
+	// replace subjects in cache
+	cache.Classes.ReplaceSubjects(subjects, updates)
+	cache.markSubjects(subjects, time.Now())
+
+	// store results
+	err = Store(cache, cache.backend)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) markSubjects(subjects []string, timestamp time.Time) {
+	if cache.Subjects == nil {
+		cache.Subjects = make(map[string]SubjectInfo)
+	}
+
+	for _, subject := range subjects {
+		cache.Subjects[subject] = SubjectInfo{Timestamp: timestamp, Rate: cache.Info.Rate}
+		cache.loaded[subject] = true
+		cache.dirty[subject] = true
+	}
+}
+
+func (cache *ClassCache) FetchDetails(ctx context.Context, CRNs []int) (err error) {
+	log.Println("Performing detail update")
+
+	// serve whatever details are cached
+	if cache.offline {
+		cache.warnStale("section details", time.Time{}, "offline, missing details are not fetched")
+		return nil
+	}
+
+	// keep other processes from writing during update
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	// fetch details that are not already cached
+	client := cache.fetcher()
+	err = client.FetchDetails(ctx, &cache.Classes, CRNs, cache.OptCache.Options.Term(client.Term))
+	if err != nil {
+		return
+	}
+
+	for _, CRN := range CRNs {
+		cache.dirty[cache.Classes.Map[CRN].GetSubject()] = true
+	}
+
+	// store results
+	err = Store(cache, cache.backend)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Detail update complete")
+	return nil
+}
+
+func (cache ClassCache) GetInfo() (info CacheInfo) {
+	return cache.Info
+}
+
+func (cache ClassCache) IsStale() (stale bool) {
+	// subjects are refreshed on demand instead of all at once
+	return cache.Info.Timestamp.IsZero()
+}
+
+func (cache *ClassCache) Save(backend Backend) (err error) {
+	// keep whole cache in one record
+	if !backend.Indexed() {
+		blob, err := json.Marshal(cache)
+		if err != nil {
+			return err
+		}
+
+		return backend.WriteRecord(cache.Info.Filename, blob)
+	}
+
+	// write changed subjects to index
+	dirty := make([]string, 0, len(cache.dirty))
+	for subject := range cache.dirty {
+		dirty = append(dirty, subject)
+	}
+
+	err = backend.WriteSubjects(dirty, cache.Classes)
+	if err != nil {
+		return
+	}
+
+	// write everything else as record
+	meta := *cache
+	meta.Classes = ClassList{}
+	blob, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+
+	err = backend.WriteRecord(cache.Info.Filename, blob)
+	if err != nil {
+		return
+	}
+
+	cache.dirty = make(map[string]bool)
+	return nil
+}
+
+func (cache *ClassCache) Load(backend Backend) (err error) {
+	blob, err := readRecord(backend, cache.Info.Filename)
+	if err != nil {
+		return
+	}
+
+	// keep configured directories and rates over stored ones
+	info, optInfo := cache.Info, cache.OptCache.Info
+	err = json.Unmarshal(blob, &cache)
+	if err != nil {
+		return
+	}
+	cache.Info.Directory, cache.OptCache.Info.Directory = info.Directory, optInfo.Directory
+	cache.Info.Rate, cache.OptCache.Info.Rate = info.Rate, optInfo.Rate
+
+	// classes are loaded per subject from indexed stores
+	cache.loaded = make(map[string]bool)
+	if backend.Indexed() {
+		cache.Classes.Init()
+	} else {
+		for subject := range cache.Subjects {
+			cache.loaded[subject] = true
+		}
+	}
+
+	return nil
+}
+
+func (cache *ClassCache) FetchData(ctx context.Context, client *Client) (err error) {
+	timestamp := time.Now()
+
+	// get html for current options
+	var input FormInput
+	input.Init(cache.OptCache.Options, client.Term)
+	classes, err := client.ParseParallel(ctx, input, cache.mode)
+	subjects, err := client.partialResult(cache.mode, input.Subjects, err)
+	if err != nil {
+		return
+	}
+
+	// only replace data once fetch succeeded
+	cache.Info.Timestamp = timestamp
+	cache.Classes.ReplaceSubjects(subjects, classes)
+	cache.markSubjects(subjects, cache.Info.Timestamp)
+	return nil
+}
+
+/* OptionsCache Functions */
+func (cache *OptionsCache) Init() {
+	cache.Version = CacheVersion
+	cache.Info.Init(OptionsCacheFilename, DefaultOptionsRate)
+}
+
+func (cache OptionsCache) GetInfo() (info CacheInfo) {
+	return cache.Info
+}
+
+func (cache OptionsCache) IsStale() (stale bool) {
+	return cache.Info.IsStale()
+}
+
+func (cache *OptionsCache) Save(backend Backend) (err error) {
+	blob, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+
+	return backend.WriteRecord(cache.Info.Filename, blob)
+}
+
+func (cache *OptionsCache) Load(backend Backend) (err error) {
+	blob, err := readRecord(backend, cache.Info.Filename)
+	if err != nil {
+		return
+	}
+
+	// keep configured directory and rate over stored ones
+	info := cache.Info
+	err = json.Unmarshal(blob, &cache)
+	cache.Info.Directory, cache.Info.Rate = info.Directory, info.Rate
+	return err
+}
+
+func (cache *OptionsCache) FetchData(ctx context.Context, client *Client) (err error) {
+	timestamp := time.Now()
+
+	options, err := client.Source.Options(ctx, client, client.Term)
+	if err != nil {
+		return
+	}
+
+	// only replace data once fetch succeeded
+	cache.Info.Timestamp = timestamp
+	cache.Options = options
+	return nil
+}
+
+/* StaleWarning Receivers */
+func (warning *StaleWarning) Error() string {
+	age := "unknown age"
+	if !warning.Timestamp.IsZero() {
+		age = time.Now().Sub(warning.Timestamp).Round(time.Minute).String() + " old"
+	}
+
+	return fmt.Sprintf("using cached %s (%s, %s)", warning.What, age, warning.Reason)
+}
+
+/* Cache Functions */
+func Store(cache Cache, backend Backend) (err error) {
+	if cache == nil || backend == nil {
+		return ErrNoCache
+	}
+
+	log.Println("Writing cache data to store")
+
+	// encode and write to store
+	err = cache.Save(backend)
+	if err != nil {
+		return
+	}
+
+	return nil
+}
+
+func writeFileAtomic(filename string, blob []byte, perm os.FileMode) (err error) {
+	// write to temp file in same directory so rename stays on one filesystem
+	temp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(blob)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return
+	}
+
+	err = os.Chmod(temp.Name(), perm)
+	if err != nil {
+		return
+	}
+
+	// replace old file in one step so readers never see partial data
+	return os.Rename(temp.Name(), filename)
+}
+
+// Restore reads cache from backend, fetching it with client if missing or
+// stale. Stale data is served with a warning if it can't be fetched.
+func Restore(ctx context.Context, client *Client, cache Cache, backend Backend, offline bool) (err error) {
+	if cache == nil || backend == nil {
+		return ErrNoCache
+	}
+
+	log.Println("Restoring cache from store")
+
+	// read and decode stored data
+	err = cache.Load(backend)
+	if err == ErrCacheTooNew {
+		return err
+	}
+
+	stored := err != ErrNotStored
+	valid := err == nil
+	if stored && !valid {
+		log.Println("Stored cache data invalid:", err)
+	}
+
+	// perform refresh
+	if !stored || !valid || cache.IsStale() {
+		usable := stored && valid
+
+		// serve stored data without fetching
+		if offline {
+			if !usable {
+				return ErrOfflineNoCache
+			}
+
+			client.warn(&StaleWarning{What: cache.GetInfo().Filename, Timestamp: cache.GetInfo().Timestamp, Reason: "offline"})
+			return nil
+		}
+
+		log.Println("Fetching cache data for refresh")
+
+		// get new data, falling back to stored data unless canceled
+		err = cache.FetchData(ctx, client)
+		if err != nil && usable && ctx.Err() == nil {
+			client.warn(&StaleWarning{What: cache.GetInfo().Filename, Timestamp: cache.GetInfo().Timestamp, Reason: "refresh failed: " + err.Error()})
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		log.Println("Storing new data")
+
+		// store new data
+		err = Store(cache, backend)
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
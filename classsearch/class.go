@@ -0,0 +1,200 @@
+package classsearch
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lyokum/update"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoClass = errors.New("Class not found")
+)
+
+// CRNError is an error with a particular section, e.g. ErrNoClass.
+type CRNError struct {
+	CRN int
+	Err error
+}
+
+type Class struct {
+	Section    string
+	Title      string
+	Credits    string
+	Max        int
+	Open       int
+	CRN        int
+	Instructor string
+	Time       string
+	Begin      string
+	End        string
+	Location   string
+	Attributes []string       `json:",omitempty"`
+	Detail     *SectionDetail `json:",omitempty"`
+}
+
+type NotifInfo struct {
+	SendUpdate bool
+	SendText   bool
+	Server     string
+	Phone      string
+	Provider   string
+}
+
+type FilterInfo struct {
+	Open        bool
+	Transcript  *Transcript
+	CRNs        []int
+	Names       []*regexp.Regexp
+	Professors  []*regexp.Regexp
+	Departments []*regexp.Regexp
+	Attributes  [][]string // each group needs one matching attribute code
+	Courses     []string   // e.g. CSE 30341
+	After       int        // earliest meeting start in minutes after midnight, 0 for any
+	Before      int        // latest meeting end in minutes after midnight, 0 for any
+}
+
+/* FilterInfo Receivers */
+func (info FilterInfo) AttributeCodes() (codes []string) {
+	found := make(map[string]bool)
+	for _, group := range info.Attributes {
+		for _, code := range group {
+			if !found[code] {
+				found[code] = true
+				codes = append(codes, code)
+			}
+		}
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+/* Class Receivers */
+func (class Class) GetSubject() (subject string) {
+	re := regexp.MustCompile("^[A-Z]+")
+	return re.FindString(class.Section)
+}
+
+func (class Class) Course() (course string) {
+	// drop section number, e.g. "CSE 30341 - 01"
+	return NormalizeCourse(class.Section)
+}
+
+func (class Class) Filter(info FilterInfo) (isValid bool) {
+	isValid = true
+
+	// check open
+	if info.Open && class.Open == 0 {
+		isValid = false
+	}
+
+	// check CRNs
+	if isValid && len(info.CRNs) > 0 {
+		isValid = false
+		for _, CRN := range info.CRNs {
+			if class.CRN == CRN {
+				isValid = true
+				break
+			}
+		}
+	}
+
+	// check Names
+	if isValid && len(info.Names) > 0 {
+		isValid = false
+		for _, expr := range info.Names {
+			if expr.MatchString(strings.ToLower(class.Title)) {
+				isValid = true
+				break
+			}
+		}
+	}
+
+	// check Professors
+	if isValid && len(info.Professors) > 0 {
+		isValid = MatchProfessor(info.Professors, class.Instructor)
+	}
+
+	// check Departments
+	if isValid && len(info.Departments) > 0 {
+		isValid = false
+		for _, expr := range info.Departments {
+			if expr.MatchString(strings.ToLower(class.GetSubject())) {
+				isValid = true
+				break
+			}
+		}
+	}
+
+	// check Courses
+	if isValid && len(info.Courses) > 0 {
+		isValid = false
+		for _, course := range info.Courses {
+			if class.Course() == NormalizeCourse(course) {
+				isValid = true
+				break
+			}
+		}
+	}
+
+	// check meeting times
+	if isValid && (info.After > 0 || info.Before > 0) {
+		isValid = class.MeetsBetween(info.After, info.Before)
+	}
+
+	// check Attributes
+	if isValid && len(info.Attributes) > 0 {
+		for _, group := range info.Attributes {
+			if !class.HasAttribute(group) {
+				isValid = false
+				break
+			}
+		}
+	}
+
+	// check eligibility
+	if isValid && info.Transcript != nil {
+		if class.CheckEligibility(*info.Transcript).Status == EligibleFail {
+			isValid = false
+		}
+	}
+
+	return isValid
+}
+
+func (class Class) HasAttribute(codes []string) bool {
+	for _, code := range codes {
+		for _, attribute := range class.Attributes {
+			if attribute == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (class Class) Notify(info NotifInfo, wg *sync.WaitGroup) {
+	alert, fired := DefaultRules[0].Evaluate(nil, &class)
+	if fired {
+		alert.Notify(info, wg)
+	}
+}
+
+func (class Class) sendText(update update.Update, info NotifInfo) {
+	cmd := exec.Command("mail-send", "-r", info.Phone+"@"+info.Provider, update.Subject, update.Body)
+	cmd.Run()
+}
+
+/* CRNError Receivers */
+func (err *CRNError) Error() string {
+	return fmt.Sprintf("CRN %d: %s", err.CRN, err.Err)
+}
+
+func (err *CRNError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,122 @@
+package classsearch
+
+import (
+	"fmt"
+	"github.com/lyokum/update"
+)
+
+type ClassList struct {
+	Map  map[int]Class // maps CRNs to classes
+	List []Class
+}
+
+/* ClassList Receivers */
+func (classes *ClassList) Init() {
+	classes.List = make([]Class, 0, 20)
+	classes.Map = make(map[int]Class)
+}
+
+func (classlist *ClassList) Generate(classes []Class) {
+	classlist.Init()
+	classlist.List = classes
+
+	// fill map
+	for _, class := range classes {
+		classlist.Map[class.CRN] = class
+	}
+}
+
+func (target *ClassList) ReplaceSubjects(subjects []string, source ClassList) {
+	replaced := make(map[string]bool)
+	for _, subject := range subjects {
+		replaced[subject] = true
+	}
+
+	// keep classes from other subjects
+	old := target.Map
+	list := target.List
+	target.Init()
+	for _, class := range list {
+		if !replaced[class.GetSubject()] {
+			target.Add(class)
+		}
+	}
+
+	// add new classes, keeping previously fetched details and attributes
+	for _, class := range source.List {
+		if class.Detail == nil {
+			class.Detail = old[class.CRN].Detail
+		}
+		if class.Attributes == nil {
+			class.Attributes = old[class.CRN].Attributes
+		}
+
+		target.Add(class)
+	}
+}
+
+func (classes *ClassList) Add(class Class) {
+	classes.List = append(classes.List, class)
+	classes.Map[class.CRN] = class
+}
+
+func (classes *ClassList) Set(class Class) {
+	classes.Map[class.CRN] = class
+
+	// replace in list
+	for i, l_class := range classes.List {
+		if l_class.CRN == class.CRN {
+			classes.List[i] = class
+		}
+	}
+}
+
+func (classes ClassList) Filter(info FilterInfo) (filteredList ClassList) {
+	filteredList.Init()
+
+	// filter elements
+	for _, class := range classes.Map {
+		if class.Filter(info) {
+			filteredList.Add(class)
+		}
+	}
+
+	return filteredList
+}
+
+// Notify sends the summary and an alert for each open class and returns the
+// alerts, e.g. for the caller to print the ones that are Printed.
+func (classes ClassList) Notify(info NotifInfo) (alerts []Alert) {
+	alerts = make([]Alert, 0, len(classes.List))
+	for _, class := range classes.List {
+		current := class
+		if alert, fired := DefaultRules[0].Evaluate(nil, &current); fired {
+			alerts = append(alerts, alert)
+		}
+	}
+
+	classes.SendSummary(info)
+	SendAlerts(info, alerts)
+	return alerts
+}
+
+// SendSummary sends whether each class is open as one update, as check has
+// always done with --update.
+func (classes ClassList) SendSummary(info NotifInfo) {
+	if !info.SendUpdate {
+		return
+	}
+
+	var notif update.Update
+	notif.Subject = "CLASSES"
+	for _, class := range classes.List {
+		available := "X"
+		if class.Open > 0 {
+			available = "O"
+		}
+
+		notif.Body += fmt.Sprintf("%s: %s %s %s\n", available, class.Section, class.Title, class.Instructor)
+	}
+
+	notif.SendRequest(info.Server)
+}
@@ -0,0 +1,285 @@
+package classsearch
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Client gets class data from the class search site, going through Cache
+// when one is set. Problems that don't stop a request, like stale data
+// being served, are kept for the caller in Warnings.
+type Client struct {
+	Cache   *ClassCache // fetch everything from site if nil
+	Mode    FetchMode
+	Offline bool // only serve cached data
+
+	Source   Source     // site classes are fetched from
+	Term     string     // searched if offered, otherwise the newest term
+	Limits   *Throttle  // throttles every request sent to Source
+	Fixtures FixtureDir // records or replays pages fetched from Source
+
+	mutex    sync.Mutex
+	warnings []error
+}
+
+/* Client Functions */
+// NewClient fetches from Notre Dame's class search through cache, which may
+// be nil.
+func NewClient(cache *ClassCache) (client *Client) {
+	client = &Client{
+		Cache:  cache,
+		Source: NDSource{Site: DefaultSite},
+		Term:   DefaultTerm,
+		Limits: NewThrottle(DefaultParallel, DefaultRate, DefaultJitter),
+	}
+
+	// cache fetches through client too
+	if cache != nil {
+		cache.client = client
+	}
+
+	return client
+}
+
+/* Client Receivers */
+// Warnings returns the warnings since it was last called, e.g. a
+// StaleWarning or a FetchError for subjects that were skipped.
+func (client *Client) Warnings() (warnings []error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	warnings, client.warnings = client.warnings, nil
+	return warnings
+}
+
+func (client *Client) Options(ctx context.Context) (opts SearchOptions, err error) {
+	if client.Cache != nil {
+		err = client.Cache.Require(ctx, NeedOptions)
+		return client.Cache.OptCache.Options, err
+	}
+
+	// nothing to serve without a cache
+	if client.Offline {
+		return opts, ErrOfflineNoCache
+	}
+
+	return client.Source.Options(ctx, client, client.Term)
+}
+
+// Classes gets every class in the subjects query could match, unfiltered.
+func (client *Client) Classes(ctx context.Context, query FilterInfo) (classes ClassList, err error) {
+	log.Println("Getting classes for query")
+
+	if client.Cache == nil {
+		return client.fetchClasses(ctx, query)
+	}
+
+	err = client.Cache.Require(ctx, NeedClasses)
+	if err != nil {
+		return
+	}
+
+	// load and refresh subjects the query could match
+	subjects := client.Cache.SubjectsFor(query)
+	err = client.Cache.LoadSubjects(subjects)
+	if err != nil {
+		return
+	}
+
+	err = client.Cache.RefreshSubjects(ctx, subjects)
+	if err != nil {
+		return
+	}
+
+	if len(query.Attributes) > 0 {
+		err = client.Cache.RefreshAttributes(ctx, query.AttributeCodes())
+		if err != nil {
+			return
+		}
+	}
+
+	return client.Cache.Classes, nil
+}
+
+func (client *Client) Search(ctx context.Context, query FilterInfo) (classes ClassList, err error) {
+	classes, err = client.Classes(ctx, query)
+	if err != nil {
+		return
+	}
+
+	return classes.Filter(query), nil
+}
+
+// Update refetches the subjects of CRNs even if they aren't stale.
+func (client *Client) Update(ctx context.Context, CRNs []int) (err error) {
+	// uncached data is always fresh
+	if client.Cache == nil || len(CRNs) == 0 {
+		return nil
+	}
+
+	return client.Cache.FetchUpdates(ctx, CRNs)
+}
+
+// Details fills in section details of classes.
+func (client *Client) Details(ctx context.Context, classes ClassList) (detailed ClassList, err error) {
+	CRNs := make([]int, 0, len(classes.Map))
+	for CRN := range classes.Map {
+		CRNs = append(CRNs, CRN)
+	}
+
+	// fetch details without storing if not caching
+	if client.Cache == nil {
+		if client.Offline {
+			return classes, nil
+		}
+
+		opts, err := client.Options(ctx)
+		if err != nil {
+			return classes, err
+		}
+
+		err = client.FetchDetails(ctx, &classes, CRNs, opts.Term(client.Term))
+		return classes, err
+	}
+
+	// fetch missing details into cache
+	err = client.Cache.FetchDetails(ctx, CRNs)
+	if err != nil {
+		return classes, err
+	}
+
+	return client.Cache.Classes.Filter(FilterInfo{CRNs: CRNs}), nil
+}
+
+// Poll refetches the subjects targets could match, even if they aren't
+// stale. Targets are resolved against the returned classes.
+func (client *Client) Poll(ctx context.Context, targets []WatchTarget) (classes ClassList, err error) {
+	if len(targets) == 0 {
+		return classes, ErrNoTargets
+	}
+
+	if client.Cache == nil {
+		return client.fetchClasses(ctx, watchQuery(targets))
+	}
+
+	return client.pollCache(ctx, targets)
+}
+
+// Attributes tags classes with which of codes they satisfy.
+func (client *Client) Attributes(ctx context.Context, classes ClassList, codes []string) (tagged ClassList, err error) {
+	if client.Cache == nil {
+		if client.Offline {
+			return classes, ErrOfflineNoCache
+		}
+
+		opts, err := client.Options(ctx)
+		if err != nil {
+			return classes, err
+		}
+
+		tags, err := client.FetchAttributes(ctx, opts, codes)
+		if err != nil {
+			return classes, err
+		}
+
+		TagClasses(&classes, tags)
+		return classes, nil
+	}
+
+	err = client.Cache.RefreshAttributes(ctx, codes)
+	if err != nil {
+		return classes, err
+	}
+
+	CRNs := make([]int, 0, len(classes.Map))
+	for CRN := range classes.Map {
+		CRNs = append(CRNs, CRN)
+	}
+
+	return client.Cache.Classes.Filter(FilterInfo{CRNs: CRNs}), nil
+}
+
+// Section gets one class along with its section details.
+func (client *Client) Section(ctx context.Context, CRN int) (class Class, err error) {
+	classes, err := client.Search(ctx, FilterInfo{CRNs: []int{CRN}})
+	if err != nil {
+		return
+	}
+
+	if _, ok := classes.Map[CRN]; !ok {
+		return class, &CRNError{CRN: CRN, Err: ErrNoClass}
+	}
+
+	classes, err = client.Details(ctx, classes)
+	if err != nil {
+		return
+	}
+
+	return classes.Map[CRN], nil
+}
+
+func (client *Client) fetchClasses(ctx context.Context, query FilterInfo) (classes ClassList, err error) {
+	// nothing to serve without a cache
+	if client.Offline {
+		return classes, ErrOfflineNoCache
+	}
+
+	opts, err := client.Options(ctx)
+	if err != nil {
+		return
+	}
+
+	// search subjects the query could match
+	var input FormInput
+	input.Init(opts, client.Term)
+	input.Subjects = subjectsMatching(opts, query.Departments)
+
+	classes, err = client.ParseParallel(ctx, input, client.Mode)
+	_, err = client.partialResult(client.Mode, input.Subjects, err)
+	if err != nil || len(query.Attributes) == 0 {
+		return classes, err
+	}
+
+	tags, err := client.FetchAttributes(ctx, opts, query.AttributeCodes())
+	TagClasses(&classes, tags)
+	return classes, err
+}
+
+func (client *Client) pollCache(ctx context.Context, targets []WatchTarget) (classes ClassList, err error) {
+	err = client.Cache.Require(ctx, NeedClasses)
+	if err != nil {
+		return
+	}
+
+	// subjects of every target, without repeats
+	found := make(map[string]bool)
+	subjects := make([]string, 0, len(targets))
+	for _, target := range targets {
+		for _, subject := range client.Cache.SubjectsFor(target.Query()) {
+			if !found[subject] {
+				found[subject] = true
+				subjects = append(subjects, subject)
+			}
+		}
+	}
+
+	err = client.Cache.LoadSubjects(subjects)
+	if err != nil {
+		return
+	}
+
+	err = client.Cache.UpdateSubjects(ctx, subjects)
+	if err != nil {
+		return
+	}
+
+	return client.Cache.Classes, nil
+}
+
+func (client *Client) warn(warning error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	client.warnings = append(client.warnings, warning)
+}
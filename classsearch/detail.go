@@ -0,0 +1,145 @@
+package classsearch
+
+import (
+	"context"
+	"golang.org/x/net/html"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type SectionDetail struct {
+	Prerequisites string
+	Restrictions  []string
+}
+
+var (
+	// headings that start a new section on the detail page
+	detailHeadings = regexp.MustCompile(`(?i)^(prerequisites|corequisites|restrictions|course attributes|cross listed|course description|registration)`)
+
+	// phrases that start a new restriction clause
+	restrictionStart = regexp.MustCompile(`(?i)(must not|must|may not|cannot) be `)
+)
+
+/* Doc Parsers */
+func GetSectionDetail(doc *html.Node) (detail SectionDetail, err error) {
+	if doc == nil {
+		return detail, ErrNodeNotFound
+	}
+
+	// flatten page text into lines
+	lines := make([]string, 0, 50)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		// skip non-visible elements
+		if node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style") {
+			return
+		}
+
+		if node.Type == html.TextNode {
+			if text := strings.TrimSpace(node.Data); text != "" {
+				lines = append(lines, text)
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(doc)
+
+	// split lines into sections by heading
+	section := ""
+	prereqs := make([]string, 0, 5)
+	restrictions := make([]string, 0, 5)
+	for _, line := range lines {
+		if heading := detailHeadings.FindString(line); heading != "" {
+			section = strings.ToLower(heading)
+
+			// keep text on same line as heading
+			line = strings.TrimSpace(strings.TrimLeft(line[len(heading):], ": "))
+			if line == "" {
+				continue
+			}
+		}
+
+		switch section {
+		case "prerequisites":
+			prereqs = append(prereqs, line)
+		case "restrictions":
+			restrictions = append(restrictions, line)
+		}
+	}
+
+	detail.Prerequisites = strings.Join(prereqs, " ")
+
+	detail.Restrictions = splitRestrictions(strings.Join(restrictions, " "))
+	return detail, nil
+}
+
+func splitRestrictions(restrictStr string) (restrictions []string) {
+	// split restrictions into clauses
+	starts := restrictionStart.FindAllStringIndex(restrictStr, -1)
+	for i, start := range starts {
+		end := len(restrictStr)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		}
+
+		restrictions = append(restrictions, strings.TrimSpace(restrictStr[start[0]:end]))
+	}
+
+	return restrictions
+}
+
+/* Client Receivers */
+func (client *Client) FetchDetails(ctx context.Context, classes *ClassList, CRNs []int, term string) (err error) {
+	log.Println("Fetching section details")
+
+	// find classes missing details
+	pending := make([]Class, 0, len(CRNs))
+	for _, CRN := range CRNs {
+		class, ok := classes.Map[CRN]
+		if !ok {
+			return &CRNError{CRN: CRN, Err: ErrNoClass}
+		}
+
+		// skip classes that already have details
+		if class.Detail == nil {
+			pending = append(pending, class)
+		}
+	}
+
+	// concurrency vars
+	lock := &sync.Mutex{}
+	errChan := make(chan error, len(pending))
+
+	// run requests on a bounded pool
+	client.Limits.Workers(len(pending), func(i int) {
+		class := pending[i]
+
+		// make request
+		detail, err := client.Source.Section(ctx, client, term, class.CRN)
+		if err != nil {
+			log.Println("Detail error with CRN", class.CRN)
+			errChan <- err
+			return
+		}
+
+		// store detail
+		class.Detail = &detail
+		lock.Lock()
+		classes.Set(class)
+		lock.Unlock()
+	})
+	close(errChan)
+
+	// fill returned error if available
+	select {
+	case err = <-errChan:
+	default:
+	}
+
+	return err
+}
@@ -0,0 +1,413 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultFakeAddress = "127.0.0.1:8080"
+)
+
+var (
+	ErrFakeDataset = errors.New("Fake server dataset has no classes")
+
+	// subjects and titles of generated dataset
+	fakeCourses = map[string][]string{
+		"CSE":  {"Fundamentals of Computing", "Data Structures", "Systems Programming", "Operating System Principles", "Compilers"},
+		"MATH": {"Calculus I", "Calculus II", "Linear Algebra", "Discrete Mathematics"},
+		"PHYS": {"General Physics I", "General Physics II", "Modern Physics"},
+		"ENGL": {"Writing and Rhetoric", "Shakespeare", "Modern Poetry"},
+	}
+	fakeAttributes = map[string][]string{
+		"CSE":  {"ENGR"},
+		"MATH": {"QUAN"},
+		"PHYS": {"SCI", "ENGR"},
+		"ENGL": {"WRIT", "LIT"},
+	}
+	fakeInstructors = []string{"Smith, John", "Doe, Jane", "Nguyen, Anh", "Garcia, Maria", "TBA"}
+	fakeTimes       = []string{"MWF - 9:25A - 10:15A", "MWF - 11:30A - 12:20P", "TR - 9:30A - 10:45A", "TR - 2:00P - 3:15P", "TBA"}
+	fakeRooms       = []string{"DeBartolo Hall 101", "DeBartolo Hall 138", "Fitzpatrick Hall 356", "Jordan Hall of Science 105", "TBA"}
+
+	// options shown on form besides subjects
+	fakeOptions = SearchOptions{
+		Terms:     map[string]string{DefaultTerm: "Fall Semester 2019"},
+		Divisions: map[string]string{"A": "All", "UG": "Undergraduate", "GR": "Graduate"},
+		Campuses:  map[string]string{"M": "Main"},
+		Attributes: map[string]string{
+			AnyAttribute: "Any",
+			"ENGR":       "Engineering Core",
+			"LIT":        "Core Literature",
+			"QUAN":       "Core Quantitative Reasoning",
+			"SCI":        "Core Science",
+			"WRIT":       "Writing Intensive",
+		},
+	}
+)
+
+// FakeServer serves pages shaped like the Class Search site from a dataset.
+type FakeServer struct {
+	ErrorRate  float64       // fraction of requests answered with 500
+	Latency    time.Duration // random delay up to this before answering
+	SeatChange time.Duration // reshuffle open seats this often if nonzero
+
+	lock    sync.Mutex
+	classes ClassList
+	random  *rand.Rand
+	changed time.Time
+}
+
+type fakeOption struct {
+	Value string
+	Name  string
+}
+
+type fakeField struct {
+	name    string
+	options map[string]string
+}
+
+type fakeSelect struct {
+	Name    string
+	Options []fakeOption
+}
+
+/* FakeServer Functions */
+func NewFakeServer(filename string) (server *FakeServer, err error) {
+	server = &FakeServer{random: rand.New(rand.NewSource(time.Now().UnixNano())), changed: time.Now()}
+
+	// generated dataset unless one is given
+	if filename == "" {
+		server.classes = FakeClasses()
+		return server, nil
+	}
+
+	blob, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	// accept plain lists or class cache records
+	var classes []Class
+	if err = json.Unmarshal(blob, &classes); err != nil {
+		var record ClassCache
+		if json.Unmarshal(blob, &record) != nil {
+			return nil, err
+		}
+		classes = record.Classes.List
+	}
+
+	if len(classes) == 0 {
+		return nil, ErrFakeDataset
+	}
+
+	server.classes.Init()
+	for _, class := range classes {
+		server.classes.Add(class)
+	}
+
+	return server, nil
+}
+
+func FakeClasses() (classes ClassList) {
+	classes.Init()
+	random := rand.New(rand.NewSource(1))
+
+	subjects := make([]string, 0, len(fakeCourses))
+	for subject := range fakeCourses {
+		subjects = append(subjects, subject)
+	}
+	sort.Strings(subjects)
+
+	CRN := 10000
+	for _, subject := range subjects {
+		previous := ""
+		for i, title := range fakeCourses[subject] {
+			// every section of a course shares its number
+			course := fmt.Sprintf("%s %d", subject, 10000+10010*i+random.Intn(10)*100)
+			sections := 1 + random.Intn(3)
+			for section := 1; section <= sections; section++ {
+				CRN++
+				max := 20 + 10*random.Intn(10)
+
+				class := Class{
+					Section:    fmt.Sprintf("%s - %02d", course, section),
+					Title:      title,
+					Credits:    strconv.Itoa(3 + random.Intn(2)),
+					Max:        max,
+					Open:       random.Intn(max + 1),
+					CRN:        CRN,
+					Instructor: fakeInstructors[random.Intn(len(fakeInstructors))],
+					Time:       fakeTimes[random.Intn(len(fakeTimes))],
+					Begin:      "08/20",
+					End:        "12/06",
+					Location:   fakeRooms[random.Intn(len(fakeRooms))],
+					Attributes: fakeAttributes[subject],
+				}
+
+				// later courses build on the one before
+				if previous != "" {
+					class.Detail = &SectionDetail{Prerequisites: previous}
+				}
+
+				classes.Add(class)
+			}
+			previous = course
+		}
+	}
+
+	return classes
+}
+
+/* FakeServer Receivers */
+func (server *FakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Println("Fake server", r.Method, r.URL)
+
+	// simulate slow or failing site
+	server.lock.Lock()
+	delay := time.Duration(0)
+	if server.Latency > 0 {
+		delay = time.Duration(server.random.Int63n(int64(server.Latency)))
+	}
+	fail := server.random.Float64() < server.ErrorRate
+	server.lock.Unlock()
+
+	time.Sleep(delay)
+	if fail {
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch r.URL.Path {
+	case searchPath:
+		err = server.search(w, r)
+	case detailPath:
+		err = server.detail(w, r)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	if err != nil {
+		log.Println("Fake server error:", err)
+	}
+}
+
+func (server *FakeServer) search(w http.ResponseWriter, r *http.Request) (err error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.changeSeats()
+
+	// form page until subjects are chosen
+	subjects := r.Form["SUBJ"]
+	if len(subjects) == 0 {
+		return fakeFormPage.Execute(w, server.selects())
+	}
+
+	if term := r.Form.Get("TERM"); term != "" && fakeOptions.Terms[term] == "" {
+		return fakeResultPage.Execute(w, []Class{})
+	}
+
+	// refuse options the form never offered
+	for _, field := range server.fields() {
+		value := r.Form.Get(field.name)
+		if field.name != "TERM" && field.name != "SUBJ" && value != "" && field.options[value] == "" {
+			err = fmt.Errorf("unknown %s option %q", field.name, value)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, subject := range subjects {
+		wanted[subject] = true
+	}
+
+	attribute := r.Form.Get("ATTR")
+	anyAttribute := attribute == "" || attribute == AnyAttribute
+	division := r.Form.Get("DIVS")
+	credit := r.Form.Get("CREDIT")
+
+	// every class is on main campus
+	classes := make([]Class, 0, 20)
+	for _, class := range server.classes.List {
+		if !wanted[class.GetSubject()] || !(anyAttribute || class.HasAttribute([]string{attribute})) {
+			continue
+		}
+
+		if division != "" && division != "A" && fakeDivision(class) != division {
+			continue
+		}
+
+		if credit != "" && credit != "A" && class.Credits != credit {
+			continue
+		}
+
+		classes = append(classes, class)
+	}
+
+	return fakeResultPage.Execute(w, classes)
+}
+
+func (server *FakeServer) detail(w http.ResponseWriter, r *http.Request) (err error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	CRN, err := strconv.Atoi(r.Form.Get("CRN"))
+	class, ok := server.classes.Map[CRN]
+	if err != nil || !ok {
+		http.NotFound(w, r)
+		return err
+	}
+
+	detail := SectionDetail{}
+	if class.Detail != nil {
+		detail = *class.Detail
+	}
+
+	return fakeDetailPage.Execute(w, struct {
+		Class  Class
+		Detail SectionDetail
+	}{class, detail})
+}
+
+func (server *FakeServer) changeSeats() {
+	if server.SeatChange <= 0 || time.Since(server.changed) < server.SeatChange {
+		return
+	}
+	server.changed = time.Now()
+
+	// open or fill a few seats in each class
+	for i, class := range server.classes.List {
+		class.Open += server.random.Intn(5) - 2
+		if class.Open < 0 {
+			class.Open = 0
+		} else if class.Open > class.Max {
+			class.Open = class.Max
+		}
+
+		server.classes.List[i] = class
+		server.classes.Map[class.CRN] = class
+	}
+}
+
+// fields are the selects of the search form and their options.
+func (server *FakeServer) fields() (fields []fakeField) {
+	subjects := make(map[string]string)
+	credits := map[string]string{"A": "All"}
+	for _, class := range server.classes.List {
+		subjects[class.GetSubject()] = class.GetSubject()
+		credits[class.Credits] = class.Credits
+	}
+
+	return []fakeField{
+		{"TERM", fakeOptions.Terms},
+		{"DIVS", fakeOptions.Divisions},
+		{"CAMPUS", fakeOptions.Campuses},
+		{"SUBJ", subjects},
+		{"ATTR", fakeOptions.Attributes},
+		{"CREDIT", credits},
+	}
+}
+
+func (server *FakeServer) selects() (selects []fakeSelect) {
+	for _, field := range server.fields() {
+		options := make([]fakeOption, 0, len(field.options))
+		for value, name := range field.options {
+			options = append(options, fakeOption{value, name})
+		}
+		sort.Slice(options, func(i, j int) bool { return options[i].Value < options[j].Value })
+
+		selects = append(selects, fakeSelect{field.name, options})
+	}
+
+	return selects
+}
+
+/* Fake Functions */
+// fakeDivision is UG or GR by course number, graduate courses being 60000
+// and up.
+func fakeDivision(class Class) string {
+	fields := strings.Fields(class.Section)
+	if len(fields) > 1 {
+		if number, err := strconv.Atoi(fields[1]); err == nil && number >= 60000 {
+			return "GR"
+		}
+	}
+
+	return "UG"
+}
+
+/* Fake Pages */
+var fakeFormPage = template.Must(template.New("form").Parse(`<html>
+<head><title>Class Search</title></head>
+<body>
+<form method="post" action="` + searchPath + `">
+{{range .}}<select name="{{.Name}}">
+{{range .Options}}<option value="{{.Value}}">{{.Name}}</option>
+{{end}}</select>
+{{end}}</form>
+</body>
+</html>
+`))
+
+var fakeResultPage = template.Must(template.New("results").Funcs(template.FuncMap{
+	"instructors": func(raw string) []string { return instructorSplitter.Split(raw, -1) },
+}).Parse(`<html>
+<head><title>Class Search</title></head>
+<body>
+<table id="resulttable">
+<thead><tr><th>Course - Sec</th><th>Title</th><th>Cr</th><th>St</th><th>Max</th><th>Opn</th><th>Xlst</th><th>CRN</th><th>Syl</th><th>Instructor</th><th>When</th><th>Begin</th><th>End</th><th>Where</th></tr></thead>
+<tbody>
+{{range .}}<tr>
+<td><a href="` + detailPath + `?CRN={{.CRN}}">{{.Section}}</a></td>
+<td>{{.Title}}</td>
+<td>{{.Credits}}</td>
+<td>{{if gt .Open 0}}A{{else}}C{{end}}</td>
+<td>{{.Max}}</td>
+<td>{{.Open}}</td>
+<td></td>
+<td>{{.CRN}}</td>
+<td></td>
+<td>{{range instructors .Instructor}}{{if eq . "TBA"}}TBA{{else}}<a href="#">{{.}}</a><br>{{end}}{{end}}</td>
+<td>{{.Time}}</td>
+<td>{{.Begin}}</td>
+<td>{{.End}}</td>
+<td>{{.Location}}</td>
+</tr>
+{{else}}<tr><td colspan="14">No courses found.</td></tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
+
+var fakeDetailPage = template.Must(template.New("detail").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<html>
+<head><title>Section Info</title></head>
+<body>
+<h2>{{.Class.Section}} {{.Class.Title}}</h2>
+{{if .Detail.Prerequisites}}<p>Prerequisites: {{.Detail.Prerequisites}}</p>
+{{end}}{{if .Detail.Restrictions}}<p>Restrictions: {{join .Detail.Restrictions " "}}</p>
+{{end}}<p>Course Description: Generated by cscli fake-server.</p>
+</body>
+</html>
+`))
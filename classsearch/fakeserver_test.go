@@ -0,0 +1,81 @@
+package classsearch
+
+import (
+	"golang.org/x/net/html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestFakeServerFilters(t *testing.T) {
+	server, err := NewFakeServer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.classes.Init()
+	server.classes.Add(Class{CRN: 10001, Section: "CSE 20289 - 01", Title: "Systems Programming", Credits: "3"})
+	server.classes.Add(Class{CRN: 10002, Section: "CSE 30341 - 01", Title: "Operating Systems", Credits: "4"})
+	server.classes.Add(Class{CRN: 10003, Section: "CSE 60212 - 01", Title: "Computer Graphics", Credits: "3"})
+
+	site := httptest.NewServer(server)
+	defer site.Close()
+
+	tests := []struct {
+		name   string
+		form   url.Values
+		status int
+		CRNs   []int
+	}{
+		{"all", url.Values{"DIVS": {"A"}, "CAMPUS": {"M"}, "CREDIT": {"A"}}, http.StatusOK, []int{10001, 10002, 10003}},
+		{"undergraduate", url.Values{"DIVS": {"UG"}}, http.StatusOK, []int{10001, 10002}},
+		{"graduate", url.Values{"DIVS": {"GR"}}, http.StatusOK, []int{10003}},
+		{"credits", url.Values{"CREDIT": {"3"}}, http.StatusOK, []int{10001, 10003}},
+		{"both", url.Values{"DIVS": {"UG"}, "CREDIT": {"4"}}, http.StatusOK, []int{10002}},
+		{"unknown division", url.Values{"DIVS": {"LAW"}}, http.StatusBadRequest, nil},
+		{"unknown campus", url.Values{"CAMPUS": {"SB"}}, http.StatusBadRequest, nil},
+		{"unknown credits", url.Values{"CREDIT": {"5"}}, http.StatusBadRequest, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.form.Set("TERM", DefaultTerm)
+			test.form.Set("SUBJ", "CSE")
+
+			resp, err := http.PostForm(site.URL+searchPath, test.form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, test.status)
+			}
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			doc, err := html.Parse(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			classes, err := GetClasses(doc, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(classes.List) != len(test.CRNs) {
+				t.Errorf("got %d classes, want %d", len(classes.List), len(test.CRNs))
+			}
+
+			for _, CRN := range test.CRNs {
+				if _, ok := classes.Map[CRN]; !ok {
+					t.Errorf("missing %d", CRN)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package classsearch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	fixturePageExt    = ".html"
+	fixtureRequestExt = ".json"
+)
+
+var (
+	ErrNoFixture = errors.New("No recorded response for request (record it first with --record)")
+
+	// curl flags whose values differ between machines, e.g. paths under the
+	// user's cache directory, left out of fixtures so they can be shared
+	fixtureLocalFlags = map[string]bool{"--cookie": true, "--cookie-jar": true}
+)
+
+// FixtureDir saves pages fetched from the site, keyed by request, so a
+// session can be served back later without touching the network.
+type FixtureDir struct {
+	Directory string
+	Replay    bool // serve saved pages instead of recording
+}
+
+// FixtureRequest is saved beside each page so fixtures can be inspected.
+type FixtureRequest struct {
+	URL  string
+	Args []string
+}
+
+/* FixtureDir Receivers */
+func (fixtures FixtureDir) Recording() bool {
+	return fixtures.Directory != "" && !fixtures.Replay
+}
+
+func (fixtures FixtureDir) Replaying() bool {
+	return fixtures.Directory != "" && fixtures.Replay
+}
+
+func (fixtures FixtureDir) Read(url string, args []string) (page []byte, err error) {
+	key := fixtureKey(url, args)
+	log.Println("Replaying fixture", key)
+
+	page, err = ioutil.ReadFile(filepath.Join(fixtures.Directory, key+fixturePageExt))
+	if os.IsNotExist(err) {
+		log.Println("Missing fixture for", url, strings.Join(args, " "))
+		return nil, ErrNoFixture
+	}
+
+	return page, err
+}
+
+func (fixtures FixtureDir) Write(url string, args []string, page []byte) (err error) {
+	key := fixtureKey(url, args)
+	log.Println("Recording fixture", key)
+
+	err = os.MkdirAll(fixtures.Directory, 0700)
+	if err != nil {
+		return
+	}
+
+	blob, err := json.MarshalIndent(FixtureRequest{URL: url, Args: fixtureArgs(args)}, "", "\t")
+	if err != nil {
+		return
+	}
+
+	err = writeFileAtomic(filepath.Join(fixtures.Directory, key+fixtureRequestExt), blob, CacheFileMode)
+	if err != nil {
+		return
+	}
+
+	return writeFileAtomic(filepath.Join(fixtures.Directory, key+fixturePageExt), page, CacheFileMode)
+}
+
+/* Fixture Functions */
+func fixtureKey(url string, args []string) string {
+	sum := sha256.Sum256([]byte(url + "\n" + strings.Join(fixtureArgs(args), "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fixtureArgs is args without local flags and their values.
+func fixtureArgs(args []string) (shared []string) {
+	shared = make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if fixtureLocalFlags[args[i]] {
+			i++
+			continue
+		}
+		shared = append(shared, args[i])
+	}
+
+	return shared
+}
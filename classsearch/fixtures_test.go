@@ -0,0 +1,181 @@
+package classsearch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// replaySubjects is the first and last CRN of each subject recorded in
+// testdata/fixtures, which has every section with consecutive CRNs.
+var replaySubjects = map[string][2]int{
+	"CSE":  {10001, 10009},
+	"ENGL": {10010, 10017},
+	"MATH": {10018, 10024},
+	"PHYS": {10025, 10030},
+}
+
+func TestReplaySearch(t *testing.T) {
+	client := replayClient(t)
+
+	info := FilterInfo{Departments: []*regexp.Regexp{regexp.MustCompile("^cse$")}}
+	classes, err := client.Search(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkReplayClasses(t, classes, "CSE")
+}
+
+func TestReplayCheck(t *testing.T) {
+	client := replayClient(t)
+	target := CourseTarget("MATH 30420", FilterInfo{})
+
+	// refetch subject of target like check does
+	classes, err := client.Poll(context.Background(), []WatchTarget{target})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sections := target.Resolve(classes)
+	if len(sections.List) != 3 {
+		t.Fatalf("resolved %d sections of %s, want 3", len(sections.List), target.Course)
+	}
+
+	class := sections.List[0]
+	if class.Section != "MATH 30420 - 01" || class.Title != "Linear Algebra" || class.Open != 16 {
+		t.Errorf("got %s %s with %d open, want MATH 30420 - 01 Linear Algebra with 16 open", class.Section, class.Title, class.Open)
+	}
+}
+
+func TestReplayRefresh(t *testing.T) {
+	cache := replayClient(t).Cache
+
+	err := cache.Refresh(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cache.OptCache.Options.Subjects) != len(replaySubjects) {
+		t.Errorf("refreshed %d subjects, want %d", len(cache.OptCache.Options.Subjects), len(replaySubjects))
+	}
+
+	for subject := range replaySubjects {
+		checkReplayClasses(t, cache.Classes.Filter(FilterInfo{
+			Departments: []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(strings.ToLower(subject)) + "$")},
+		}), subject)
+	}
+}
+
+func TestReplayMissing(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	client := NewClient(nil)
+	client.Fixtures = FixtureDir{Directory: dir, Replay: true}
+
+	_, err := client.Options(context.Background())
+
+	var requestErr *RequestError
+	if !errors.As(err, &requestErr) || !errors.Is(err, ErrNoFixture) {
+		t.Errorf("got %v, want RequestError for missing fixture", err)
+	}
+}
+
+func TestReplayOtherCacheDir(t *testing.T) {
+	fixtures := FixtureDir{Directory: tempDir(t)}
+	defer os.RemoveAll(fixtures.Directory)
+
+	// record banner session with cookie jar in one cache directory
+	recorded := &BannerSource{
+		Site:    "https://banner.example.edu",
+		Cookies: filepath.Join("/home/alice/.cache/cscli", bannerCookies),
+	}
+	query := url.Values{"searchTerm": {""}, "offset": {"1"}, "max": {"1000"}}
+	endpoint := recorded.Site + bannerPath + "/classSearch/getTerms?" + query.Encode()
+	err := fixtures.Write(endpoint, recorded.cookieArgs(), []byte(`[{"code":"202010","description":"Fall 2019"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// replay it with jar in another
+	source := &BannerSource{
+		Site:    recorded.Site,
+		Cookies: filepath.Join("/home/bob/.cache/cscli", bannerCookies),
+	}
+	client := NewClient(nil)
+	client.Fixtures = FixtureDir{Directory: fixtures.Directory, Replay: true}
+
+	codes, err := source.codes(context.Background(), client, "classSearch/getTerms", url.Values{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != 1 || codes[0].Code != "202010" {
+		t.Errorf("got %v, want recorded term 202010", codes)
+	}
+}
+
+// replayClient caches in an empty json store and fetches from
+// testdata/fixtures instead of the site.
+func replayClient(t *testing.T) (client *Client) {
+	t.Helper()
+
+	dir := tempDir(t)
+	cache := &ClassCache{}
+	cache.Init()
+	err := cache.SetDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = cache.Open("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		cache.Close()
+		os.RemoveAll(dir)
+	})
+
+	client = NewClient(cache)
+	client.Fixtures = FixtureDir{Directory: "testdata/fixtures", Replay: true}
+	return client
+}
+
+func tempDir(t *testing.T) (dir string) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "cscli-replay")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+// checkReplayClasses checks classes has every section of subject recorded.
+func checkReplayClasses(t *testing.T, classes ClassList, subject string) {
+	t.Helper()
+
+	CRNs := replaySubjects[subject]
+	for CRN := CRNs[0]; CRN <= CRNs[1]; CRN++ {
+		class, ok := classes.Map[CRN]
+		if !ok {
+			t.Errorf("missing %d of %s", CRN, subject)
+		} else if class.GetSubject() != subject {
+			t.Errorf("got %s for %d, want %s", class.Section, CRN, subject)
+		}
+	}
+
+	if want := CRNs[1] - CRNs[0] + 1; len(classes.List) != want {
+		t.Errorf("got %d %s classes, want %d", len(classes.List), subject, want)
+	}
+}
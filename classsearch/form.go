@@ -1,7 +1,12 @@
-package main
+package classsearch
 
 import (
 	"fmt"
+	"sort"
+)
+
+const (
+	DefaultTerm = "201910"
 )
 
 type FormInput struct {
@@ -13,8 +18,10 @@ type FormInput struct {
 	Credit    string
 }
 
-func (input *FormInput) Init(opt SearchOptions) {
-	input.Term = "201910"
+// Init searches every subject of opt in term, or the newest term if term
+// is not offered.
+func (input *FormInput) Init(opt SearchOptions, term string) {
+	input.Term = opt.Term(term)
 	input.Division = "A"
 	input.Campus = "M"
 	input.Attribute = "0ANY"
@@ -26,6 +33,9 @@ func (input *FormInput) Init(opt SearchOptions) {
 		for subj, _ := range opt.Subjects {
 			input.Subjects = append(input.Subjects, subj)
 		}
+
+		// keep requests the same between runs
+		sort.Strings(input.Subjects)
 	}
 }
 
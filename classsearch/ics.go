@@ -0,0 +1,232 @@
+package classsearch
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	icsDateFormat     = "20060102"
+	icsDateTimeFormat = "20060102T150405"
+	icsLineLimit      = 75
+)
+
+var (
+	ErrNoTermDates = errors.New("Term start/end dates not known (use --start and --end)")
+	ErrInvalidDate = errors.New("Invalid date given")
+
+	// layouts accepted for term and holiday dates
+	DateLayouts = []string{"01/02/2006", "1/2/2006", "2006-01-02", "Jan 2, 2006", "January 2, 2006", "02-Jan-2006"}
+
+	// layouts scraped class dates use, year taken from the term
+	ShortDateLayouts = []string{"01/02", "1/2"}
+
+	// maps weekdays to RFC 5545 day codes
+	icsDays = map[time.Weekday]string{
+		time.Sunday:    "SU",
+		time.Monday:    "MO",
+		time.Tuesday:   "TU",
+		time.Wednesday: "WE",
+		time.Thursday:  "TH",
+		time.Friday:    "FR",
+		time.Saturday:  "SA",
+	}
+
+	icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+)
+
+type ICSOptions struct {
+	Start    time.Time // overrides class begin date if set
+	End      time.Time // overrides class end date if set
+	Year     int       // year of class dates without one, e.g. 08/20
+	Holidays []time.Time
+}
+
+/* Date Functions */
+func ParseDate(date string) (parsed time.Time, err error) {
+	date = strings.TrimSpace(date)
+
+	for _, layout := range DateLayouts {
+		parsed, err = time.ParseInLocation(layout, date, time.Local)
+		if err == nil {
+			return parsed, nil
+		}
+	}
+
+	return parsed, ErrInvalidDate
+}
+
+// ParseTermDate parses a class date, using year when the date has none.
+func ParseTermDate(date string, year int) (parsed time.Time, err error) {
+	parsed, err = ParseDate(date)
+	if err == nil || year == 0 {
+		return
+	}
+
+	for _, layout := range ShortDateLayouts {
+		parsed, err = time.ParseInLocation(layout, strings.TrimSpace(date), time.Local)
+		if err == nil {
+			return parsed.AddDate(year-parsed.Year(), 0, 0), nil
+		}
+	}
+
+	return parsed, ErrInvalidDate
+}
+
+// TermYear gets the calendar year of a term code like 201910, where spring
+// (20) and summer (30) terms fall in the year after the one in the code.
+func TermYear(term string) (year int, err error) {
+	if len(term) < 4 {
+		return 0, ErrInvalidDate
+	}
+
+	year, err = strconv.Atoi(term[:4])
+	if err != nil {
+		return 0, ErrInvalidDate
+	}
+
+	switch term[4:] {
+	case "20", "30":
+		year++
+	}
+
+	return year, nil
+}
+
+func LoadHolidays(filename string) (holidays []time.Time, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	// read one date per line, skipping blanks and comments
+	holidays = make([]time.Time, 0, 10)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		holiday, err := ParseDate(line)
+		if err != nil {
+			return holidays, err
+		}
+
+		holidays = append(holidays, holiday)
+	}
+
+	return holidays, scanner.Err()
+}
+
+/* ICS Functions */
+func WriteICS(w io.Writer, classes []Class, opts ICSOptions) (err error) {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//lyokum//cscli//EN", "CALSCALE:GREGORIAN"}
+	stamp := time.Now().UTC().Format(icsDateTimeFormat) + "Z"
+
+	for _, class := range classes {
+		// get term dates for class
+		start, end := opts.Start, opts.End
+		if start.IsZero() {
+			start, _ = ParseTermDate(class.Begin, opts.Year)
+		}
+		if end.IsZero() {
+			end, _ = ParseTermDate(class.End, opts.Year)
+		}
+
+		if start.IsZero() || end.IsZero() {
+			return ErrNoTermDates
+		}
+
+		for i, meeting := range class.Meetings() {
+			lines = append(lines, meetingEvent(class, meeting, i, start, end, opts.Holidays, stamp)...)
+		}
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	// write folded lines with CRLF endings
+	for _, line := range lines {
+		_, err = io.WriteString(w, foldLine(line)+"\r\n")
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+func meetingEvent(class Class, meeting Meeting, index int, start, end time.Time, holidays []time.Time, stamp string) (lines []string) {
+	// find first meeting day on or after term start
+	first := start
+	for !meeting.MeetsOn(first.Weekday()) {
+		first = first.AddDate(0, 0, 1)
+		if first.After(end) {
+			return nil
+		}
+	}
+
+	// build day list
+	days := make([]string, 0, len(meeting.Days))
+	for _, day := range meeting.Days {
+		days = append(days, icsDays[day])
+	}
+
+	lines = []string{
+		"BEGIN:VEVENT",
+		fmt.Sprintf("UID:%d-%d-%s@cscli", class.CRN, index, start.Format(icsDateFormat)),
+		"DTSTAMP:" + stamp,
+		"DTSTART:" + atMinutes(first, meeting.Start).Format(icsDateTimeFormat),
+		"DTEND:" + atMinutes(first, meeting.End).Format(icsDateTimeFormat),
+		"RRULE:FREQ=WEEKLY;BYDAY=" + strings.Join(days, ",") + ";UNTIL=" + atMinutes(end, 24*60-1).Format(icsDateTimeFormat),
+		"SUMMARY:" + icsEscaper.Replace(class.Section+" "+class.Title),
+		"DESCRIPTION:" + icsEscaper.Replace(fmt.Sprintf("CRN %d\nInstructor: %s", class.CRN, class.Instructor)),
+	}
+
+	if class.Location != "" {
+		lines = append(lines, "LOCATION:"+icsEscaper.Replace(class.Location))
+	}
+
+	// exclude holidays that fall on meeting days
+	for _, holiday := range holidays {
+		if !holiday.Before(first) && !holiday.After(end) && meeting.MeetsOn(holiday.Weekday()) {
+			lines = append(lines, "EXDATE:"+atMinutes(holiday, meeting.Start).Format(icsDateTimeFormat))
+		}
+	}
+
+	return append(lines, "END:VEVENT")
+}
+
+func atMinutes(day time.Time, minutes int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, day.Location())
+}
+
+func foldLine(line string) (folded string) {
+	// split long lines into continuation lines without breaking characters
+	limit := icsLineLimit
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+
+		folded += line[:cut] + "\r\n "
+		line = line[cut:]
+
+		// continuation lines start with a space
+		limit = icsLineLimit - 1
+	}
+
+	return folded + line
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
@@ -0,0 +1,107 @@
+package classsearch
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	UnknownInstructor = "TBA"
+)
+
+var (
+	// separators between multiple instructors of one section
+	instructorSplitter = regexp.MustCompile(`\s*(;|/|\n|\band\b)\s*`)
+	extraSpaces        = regexp.MustCompile(`\s+`)
+)
+
+type InstructorSections struct {
+	Name    string
+	Classes []Class
+	Max     int
+	Open    int
+}
+
+/* Instructor Functions */
+func NormalizeInstructors(raw string) (names []string) {
+	names = make([]string, 0, 2)
+
+	for _, name := range instructorSplitter.Split(strings.TrimSpace(raw), -1) {
+		name = extraSpaces.ReplaceAllString(strings.TrimSpace(name), " ")
+
+		// skip placeholders
+		if name == "" || strings.EqualFold(name, UnknownInstructor) || strings.EqualFold(name, "staff") {
+			continue
+		}
+
+		// reorder "Last, First" to "First Last"
+		if parts := strings.SplitN(name, ",", 2); len(parts) == 2 {
+			name = strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0])
+		}
+
+		names = append(names, name)
+	}
+
+	// group unassigned sections together
+	if len(names) == 0 {
+		names = append(names, UnknownInstructor)
+	}
+
+	return names
+}
+
+func MatchProfessor(exprs []*regexp.Regexp, instructor string) bool {
+	// check raw and normalized forms of instructor names
+	candidates := append([]string{instructor}, NormalizeInstructors(instructor)...)
+
+	for _, expr := range exprs {
+		for _, candidate := range candidates {
+			if expr.MatchString(strings.ToLower(candidate)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/* ClassList Receivers */
+func (classes ClassList) ByInstructor(professors []*regexp.Regexp) (instructors []InstructorSections) {
+	groups := make(map[string]*InstructorSections)
+
+	// group sections by each of their instructors
+	for _, class := range classes.List {
+		for _, name := range NormalizeInstructors(class.Instructor) {
+			if len(professors) > 0 && !MatchProfessor(professors, name) {
+				continue
+			}
+
+			key := strings.ToLower(name)
+			group, ok := groups[key]
+			if !ok {
+				group = &InstructorSections{Name: name}
+				groups[key] = group
+			}
+
+			group.Classes = append(group.Classes, class)
+			group.Max += class.Max
+			group.Open += class.Open
+		}
+	}
+
+	// sort by name with sections in order
+	instructors = make([]InstructorSections, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.Classes, func(i, j int) bool {
+			return group.Classes[i].Section < group.Classes[j].Section
+		})
+		instructors = append(instructors, *group)
+	}
+
+	sort.Slice(instructors, func(i, j int) bool {
+		return instructors[i].Name < instructors[j].Name
+	})
+
+	return instructors
+}
@@ -0,0 +1,270 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	LedgerFilename = "notifications.json"
+
+	DefaultCooldown = time.Hour
+	LedgerRetention = time.Hour * 24 * 30
+
+	SentStatus       = "sent"
+	SuppressedStatus = "suppressed"
+	DeferredStatus   = "deferred"
+	DigestStatus     = "digest"
+)
+
+var (
+	ErrInvalidQuietHours = errors.New("Invalid quiet hours (use START-END, e.g. 22:00-7:00)")
+)
+
+// QuietHours is when alerts are held for a digest, in minutes after local
+// midnight. Start and End are equal when there are no quiet hours.
+type QuietHours struct {
+	Start int
+	End   int
+}
+
+// NotifyPolicy decides which alerts are sent, held or dropped.
+type NotifyPolicy struct {
+	Cooldown time.Duration // repeats of an alert within this are suppressed
+	Quiet    QuietHours
+}
+
+// LedgerEntry records what happened to one alert and why.
+type LedgerEntry struct {
+	Time      time.Time
+	CRN       int
+	Condition string
+	Rule      string
+	Message   string
+	Status    string
+	Reason    string    `json:",omitempty"`
+	Delivered time.Time // when a deferred alert went out in a digest
+}
+
+// Ledger is every notification decision, kept in the store.
+type Ledger struct {
+	Entries []LedgerEntry
+}
+
+/* QuietHours Functions */
+func ParseQuietHours(text string) (quiet QuietHours, err error) {
+	bounds := strings.Split(text, "-")
+	if len(bounds) != 2 {
+		return quiet, ErrInvalidQuietHours
+	}
+
+	quiet.Start, err = ParseClock(bounds[0])
+	if err != nil {
+		return quiet, ErrInvalidQuietHours
+	}
+
+	quiet.End, err = ParseClock(bounds[1])
+	if err != nil {
+		return quiet, ErrInvalidQuietHours
+	}
+
+	return quiet, nil
+}
+
+/* QuietHours Receivers */
+func (quiet QuietHours) Contains(when time.Time) bool {
+	minute := when.Hour()*60 + when.Minute()
+
+	switch {
+	case quiet.Start == quiet.End:
+		return false
+	case quiet.Start < quiet.End:
+		return minute >= quiet.Start && minute < quiet.End
+	}
+
+	// wraps past midnight
+	return minute >= quiet.Start || minute < quiet.End
+}
+
+func (quiet QuietHours) String() string {
+	if quiet.Start == quiet.End {
+		return "none"
+	}
+	return FormatClock(quiet.Start) + "-" + FormatClock(quiet.End)
+}
+
+/* Ledger Receivers */
+// Apply decides what to do with alerts raised at now and records it. Alerts
+// to send right away are returned, along with a digest of alerts held
+// during quiet hours once they are over.
+func (ledger *Ledger) Apply(alerts []Alert, policy NotifyPolicy, now time.Time) (send []Alert) {
+	quiet := policy.Quiet.Contains(now)
+
+	for _, alert := range alerts {
+		entry := LedgerEntry{
+			Time:      now,
+			CRN:       alert.Class.CRN,
+			Condition: alert.Rule.Condition.String(),
+			Rule:      alert.Rule.String(),
+			Message:   alert.Message,
+		}
+
+		// alerts still cooling down count whether sent or held
+		if last, ok := ledger.last(entry.CRN, entry.Rule); ok && now.Sub(last.Time) < policy.Cooldown {
+			entry.Status = SuppressedStatus
+			entry.Reason = fmt.Sprintf("%s %s ago, cooldown %s", last.Status, now.Sub(last.Time).Round(time.Second), policy.Cooldown)
+		} else if quiet {
+			entry.Status = DeferredStatus
+			entry.Reason = "quiet hours " + policy.Quiet.String()
+		} else {
+			entry.Status = SentStatus
+			send = append(send, alert)
+		}
+
+		ledger.Entries = append(ledger.Entries, entry)
+	}
+
+	// deliver held alerts once quiet hours are over
+	if !quiet {
+		if digest, ok := ledger.digest(now); ok {
+			send = append(send, digest)
+		}
+	}
+
+	ledger.prune(now)
+	return send
+}
+
+func (ledger Ledger) Since(since time.Time) (entries []LedgerEntry) {
+	for _, entry := range ledger.Entries {
+		if !entry.Time.Before(since) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// last finds the latest alert for CRN and rule that went out or is waiting
+// to. Rules include their condition, so e.g. open and cancelled alerts for a
+// section cool down separately.
+func (ledger Ledger) last(CRN int, rule string) (entry LedgerEntry, ok bool) {
+	for i := len(ledger.Entries) - 1; i >= 0; i-- {
+		entry = ledger.Entries[i]
+		if entry.CRN != CRN || entry.Rule != rule {
+			continue
+		}
+
+		if entry.Status == SentStatus || entry.Status == DeferredStatus {
+			return entry, true
+		}
+	}
+
+	return entry, false
+}
+
+func (ledger *Ledger) digest(now time.Time) (digest Alert, ok bool) {
+	lines := make([]string, 0, 10)
+	notifiers := make(map[string]bool)
+	everything := false
+
+	for i := range ledger.Entries {
+		entry := &ledger.Entries[i]
+		if entry.Status != DeferredStatus || !entry.Delivered.IsZero() {
+			continue
+		}
+
+		entry.Delivered = now
+		lines = append(lines, entry.Message)
+
+		// send through every notifier the held rules picked
+		rule, err := ParseAlertRule(entry.Rule)
+		if err != nil || len(rule.Notifiers) == 0 {
+			everything = true
+		}
+		for _, notifier := range rule.Notifiers {
+			notifiers[notifier] = true
+		}
+	}
+
+	if len(lines) == 0 {
+		return digest, false
+	}
+
+	digest.Subject = "<CLASS DIGEST>"
+	digest.Message = fmt.Sprintf("%d alert(s) during quiet hours:\n%s", len(lines), strings.Join(lines, "\n"))
+	if !everything {
+		for notifier := range notifiers {
+			digest.Rule.Notifiers = append(digest.Rule.Notifiers, notifier)
+		}
+		sort.Strings(digest.Rule.Notifiers)
+	}
+
+	ledger.Entries = append(ledger.Entries, LedgerEntry{
+		Time:    now,
+		Rule:    strings.Join(digest.Rule.Notifiers, ","),
+		Message: digest.Message,
+		Status:  DigestStatus,
+		Reason:  fmt.Sprintf("%d deferred alert(s)", len(lines)),
+	})
+
+	return digest, true
+}
+
+func (ledger *Ledger) prune(now time.Time) {
+	kept := ledger.Entries[:0]
+	for _, entry := range ledger.Entries {
+		// keep held alerts until they go out
+		pending := entry.Status == DeferredStatus && entry.Delivered.IsZero()
+		if pending || now.Sub(entry.Time) < LedgerRetention {
+			kept = append(kept, entry)
+		}
+	}
+
+	ledger.Entries = kept
+}
+
+/* ClassCache Receivers */
+func (cache *ClassCache) ReadLedger() (ledger Ledger, err error) {
+	blob, err := cache.backend.ReadRecord(LedgerFilename)
+	if err == ErrNotStored {
+		return ledger, nil
+	} else if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(blob, &ledger)
+	return ledger, err
+}
+
+// ApplyLedger runs alerts through the stored ledger, so repeats are caught
+// across runs and processes, and returns the alerts to send.
+func (cache *ClassCache) ApplyLedger(alerts []Alert, policy NotifyPolicy) (send []Alert, err error) {
+	// keep other processes from deciding at the same time
+	err = cache.Lock()
+	if err != nil {
+		return
+	}
+	defer cache.Unlock()
+
+	ledger, err := cache.ReadLedger()
+	if err != nil {
+		// start over if stored ledger is unreadable
+		log.Println("Stored ledger invalid:", err)
+		ledger = Ledger{}
+	}
+
+	send = ledger.Apply(alerts, policy, time.Now())
+
+	blob, err := json.Marshal(ledger)
+	if err != nil {
+		return
+	}
+
+	return send, cache.backend.WriteRecord(LedgerFilename, blob)
+}
@@ -0,0 +1,93 @@
+package classsearch
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLockWait = time.Second * 30
+	LockFilename    = "cscli.lock"
+
+	lockPollRate = time.Millisecond * 100
+)
+
+var (
+	ErrCacheLocked = errors.New("Cache is locked by another cscli process (try again later or raise --lock-wait)")
+
+	errLockHeld = errors.New("Lock held")
+)
+
+// FileLock is an advisory lock shared between cscli processes. It is
+// reentrant within a process so cache functions can call each other.
+type FileLock struct {
+	Path string
+	Wait time.Duration
+
+	mutex sync.Mutex
+	depth int
+	file  *os.File
+}
+
+/* FileLock Receivers */
+func (lock *FileLock) Lock() (err error) {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	// already held by this process
+	if lock.depth > 0 {
+		lock.depth++
+		return nil
+	}
+
+	log.Println("Acquiring lock", lock.Path)
+
+	// poll until lock is free or wait runs out
+	deadline := time.Now().Add(lock.Wait)
+	for {
+		file, err := tryLockFile(lock.Path)
+		if err == nil {
+			lock.file = file
+			lock.depth = 1
+
+			// record owner for debugging
+			file.Truncate(0)
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			return nil
+		}
+
+		if err != errLockHeld {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return ErrCacheLocked
+		}
+
+		time.Sleep(lockPollRate)
+	}
+}
+
+func (lock *FileLock) Unlock() (err error) {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+
+	if lock.depth == 0 {
+		return nil
+	}
+
+	// only release once outermost holder is done
+	lock.depth--
+	if lock.depth > 0 {
+		return nil
+	}
+
+	log.Println("Releasing lock", lock.Path)
+	err = unlockFile(lock.file)
+	lock.file = nil
+	return err
+}
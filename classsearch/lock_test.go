@@ -0,0 +1,29 @@
+package classsearch
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestClearKeepsLock(t *testing.T) {
+	cache := replayClient(t).Cache
+
+	err := cache.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// removing the file would let another process lock a new one
+	if _, err = os.Stat(cache.lock.Path); err != nil {
+		t.Errorf("lock file gone after clear: %v", err)
+	}
+
+	// and the lock is free again
+	other := &FileLock{Path: cache.lock.Path, Wait: time.Millisecond * 10}
+	err = other.Lock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Unlock()
+}
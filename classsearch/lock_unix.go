@@ -0,0 +1,33 @@
+//go:build !windows
+// +build !windows
+
+package classsearch
+
+import (
+	"os"
+	"syscall"
+)
+
+func tryLockFile(path string) (file *os.File, err error) {
+	file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, CacheFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// lock is released by the kernel if the process dies
+	err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		file.Close()
+		return nil, errLockHeld
+	} else if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func unlockFile(file *os.File) (err error) {
+	syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	return file.Close()
+}
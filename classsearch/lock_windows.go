@@ -0,0 +1,34 @@
+//go:build windows
+// +build windows
+
+package classsearch
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+func tryLockFile(path string) (file *os.File, err error) {
+	file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, CacheFileMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// lock is released by the system if the process dies
+	flags := uint32(windows.LOCKFILE_EXCLUSIVE_LOCK | windows.LOCKFILE_FAIL_IMMEDIATELY)
+	err = windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &windows.Overlapped{})
+	if err == windows.ERROR_LOCK_VIOLATION {
+		file.Close()
+		return nil, errLockHeld
+	} else if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func unlockFile(file *os.File) (err error) {
+	windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{})
+	return file.Close()
+}
@@ -0,0 +1,178 @@
+package classsearch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidTime = errors.New("Invalid time given")
+	ErrInvalidDay  = errors.New("Invalid day given")
+
+	// maps day abbreviations in class times to weekdays
+	DayLetters = map[rune]time.Weekday{
+		'M': time.Monday,
+		'T': time.Tuesday,
+		'W': time.Wednesday,
+		'R': time.Thursday,
+		'H': time.Thursday,
+		'F': time.Friday,
+		'S': time.Saturday,
+		'U': time.Sunday,
+	}
+
+	// e.g. "MWF - 10:30A - 11:20A"
+	meetingPattern = regexp.MustCompile(`(?i)([MTWRHFSU]+)\s*-\s*(\d{1,2}:\d{2}\s*[AP]M?)\s*-\s*(\d{1,2}:\d{2}\s*[AP]M?)`)
+	clockPattern   = regexp.MustCompile(`(?i)^(\d{1,2})(?::(\d{2}))?\s*([AP])?M?$`)
+)
+
+type Meeting struct {
+	Days  []time.Weekday
+	Start int // minutes after midnight
+	End   int
+}
+
+/* Meeting Functions */
+func ParseMeetings(when string) (meetings []Meeting) {
+	meetings = make([]Meeting, 0, 2)
+
+	for _, match := range meetingPattern.FindAllStringSubmatch(when, -1) {
+		var meeting Meeting
+		var err error
+
+		// get days of meeting
+		for _, letter := range strings.ToUpper(match[1]) {
+			meeting.Days = append(meeting.Days, DayLetters[letter])
+		}
+
+		// get start and end
+		meeting.Start, err = ParseClock(match[2])
+		if err != nil {
+			continue
+		}
+
+		meeting.End, err = ParseClock(match[3])
+		if err != nil {
+			continue
+		}
+
+		meetings = append(meetings, meeting)
+	}
+
+	return meetings
+}
+
+func ParseClock(clock string) (minutes int, err error) {
+	match := clockPattern.FindStringSubmatch(strings.TrimSpace(clock))
+	if match == nil {
+		return 0, ErrInvalidTime
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute := 0
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	// convert 12 hour clock
+	switch strings.ToUpper(match[3]) {
+	case "A":
+		if hour == 12 {
+			hour = 0
+		}
+	case "P":
+		if hour != 12 {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, ErrInvalidTime
+	}
+
+	return hour*60 + minute, nil
+}
+
+func ParseWeekday(day string) (weekday time.Weekday, err error) {
+	day = strings.ToLower(strings.TrimSpace(day))
+	if day == "" {
+		return weekday, ErrInvalidDay
+	}
+
+	// single letter abbreviations
+	if len(day) == 1 {
+		if weekday, ok := DayLetters[rune(strings.ToUpper(day)[0])]; ok {
+			return weekday, nil
+		}
+		return weekday, ErrInvalidDay
+	}
+
+	// full or partial day names
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.HasPrefix(strings.ToLower(weekday.String()), day) {
+			return weekday, nil
+		}
+	}
+
+	return weekday, ErrInvalidDay
+}
+
+func FormatClock(minutes int) string {
+	hour, suffix := minutes/60, "A"
+	if hour >= 12 {
+		suffix = "P"
+	}
+
+	// convert to 12 hour clock
+	if hour%12 == 0 {
+		hour = 12
+	} else {
+		hour %= 12
+	}
+
+	return fmt.Sprintf("%d:%02d%s", hour, minutes%60, suffix)
+}
+
+/* Meeting Receivers */
+func (meeting Meeting) MeetsOn(day time.Weekday) bool {
+	for _, meetDay := range meeting.Days {
+		if meetDay == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (meeting Meeting) Overlaps(other Meeting) bool {
+	for _, day := range meeting.Days {
+		if other.MeetsOn(day) && meeting.Start < other.End && other.Start < meeting.End {
+			return true
+		}
+	}
+	return false
+}
+
+/* Class Receivers */
+func (class Class) Meetings() (meetings []Meeting) {
+	return ParseMeetings(class.Time)
+}
+
+// MeetsBetween checks that every meeting of class is within start and end,
+// where zero leaves that side open. Classes without set times never match.
+func (class Class) MeetsBetween(start, end int) bool {
+	meetings := class.Meetings()
+	if len(meetings) == 0 {
+		return false
+	}
+
+	for _, meeting := range meetings {
+		if (start > 0 && meeting.Start < start) || (end > 0 && meeting.End > end) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package classsearch
+
+import (
+	"log"
+)
+
+type SearchOptions struct {
+	Terms      map[string]string
+	Divisions  map[string]string
+	Campuses   map[string]string
+	Subjects   map[string]string
+	Attributes map[string]string
+	Credits    map[string]string
+}
+
+/* SearchOptions Receivers */
+// Term is preferred if it is offered, otherwise the newest term.
+func (opts SearchOptions) Term(preferred string) (term string) {
+	if _, ok := opts.Terms[preferred]; ok || len(opts.Terms) == 0 {
+		return preferred
+	}
+
+	// fall back to newest term offered
+	for code := range opts.Terms {
+		if code > term {
+			term = code
+		}
+	}
+
+	log.Println("Term", preferred, "not offered, using", term)
+	return term
+}
@@ -0,0 +1,489 @@
+package classsearch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/lyokum/attr"
+	"golang.org/x/net/html"
+	"log"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FIXME: remove these after testing
+var _ = fmt.Printf
+
+const (
+	DefaultSite = "https://class-search.nd.edu"
+
+	searchPath = "/reg/srch/ClassSearchServlet"
+	detailPath = "/reg/srch/SectionInfoServlet"
+)
+
+const (
+	BestEffort FetchMode = iota // keep subjects that were fetched
+	FailFast                    // stop at first failed subject
+)
+
+var (
+	ErrNodeNotFound  = errors.New("Node not found")
+	ErrFieldNotFound = errors.New("Field not found")
+)
+
+type FetchMode int
+
+type SubjectError struct {
+	Subject string
+	Err     error
+}
+
+// FetchError reports every subject ParseParallel could not fetch. It is
+// also a warning when the subjects that were fetched are kept.
+type FetchError struct {
+	Total   int
+	Fetched []string
+	Failed  []SubjectError
+	Skipped []string // not attempted after a failure in FailFast mode
+}
+
+// RequestError is a request to the site that failed.
+type RequestError struct {
+	URL string
+	Err error
+}
+
+/* Doc Creation */
+func parseURL(ctx context.Context, fetcher Fetcher, url string, args ...string) (doc *html.Node, err error) {
+	formStr := strings.Join(args, " ")
+
+	// get html from request
+	page, err := fetcher.Fetch(ctx, url, args)
+	if err != nil {
+		log.Println("Error fetching form", formStr)
+		return nil, err
+	}
+
+	log.Println("Parsing HTML of response")
+
+	// parse html into tree
+	doc, err = html.Parse(bytes.NewReader(page))
+	if err != nil {
+		log.Println("Error with html.Parse for form", formStr)
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+/* Client Receivers */
+// Fetch requests url from the site with curl args, throttled by Limits and
+// recorded or replayed with Fixtures.
+func (client *Client) Fetch(ctx context.Context, url string, args []string) (page []byte, err error) {
+	// serve recorded session instead of site
+	if client.Fixtures.Replaying() {
+		page, err = client.Fixtures.Read(url, args)
+		if err != nil {
+			return nil, &RequestError{URL: url, Err: err}
+		}
+		return page, nil
+	}
+
+	// wait for turn to avoid flooding site
+	err = client.Limits.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Limits.Release()
+	log.Println("Sending request to site")
+
+	// kill curl if canceled
+	curlArgs := append([]string{"--retry", "3"}, args...)
+	cmd := exec.CommandContext(ctx, "curl", append(curlArgs, url)...)
+	page, err = cmd.Output()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if err != nil {
+		return nil, &RequestError{URL: url, Err: err}
+	}
+
+	if client.Fixtures.Recording() {
+		err = client.Fixtures.Write(url, args, page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+func (client *Client) ParseParallel(ctx context.Context, input FormInput, mode FetchMode) (classes ClassList, err error) {
+	classes.Init()
+
+	// concurrency vars
+	lock := &sync.Mutex{}
+	result := &FetchError{Total: len(input.Subjects)}
+	stop := false
+
+	// run requests on a bounded pool
+	client.Limits.Workers(len(input.Subjects), func(i int) {
+		subject := input.Subjects[i]
+
+		// don't start new subjects after a failure or cancel
+		lock.Lock()
+		if stop || ctx.Err() != nil {
+			result.Skipped = append(result.Skipped, subject)
+			lock.Unlock()
+			return
+		}
+		lock.Unlock()
+
+		// create subset of full input
+		subinput := input
+		subinput.Subjects = []string{subject}
+
+		// make request and parse request info
+		subclasses, err := client.Source.Search(ctx, client, subinput)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		if err != nil {
+			log.Println("Error with subject", subject+":", err)
+			result.Failed = append(result.Failed, SubjectError{subject, err})
+			stop = mode == FailFast
+			return
+		}
+
+		// add classes
+		for _, class := range subclasses.List {
+			classes.Add(class)
+		}
+		result.Fetched = append(result.Fetched, subject)
+	})
+
+	log.Println(result.Summary())
+
+	// partial results are never kept after a cancel
+	if ctx.Err() != nil {
+		return classes, ctx.Err()
+	}
+
+	if len(result.Failed) > 0 {
+		return classes, result
+	}
+
+	return classes, nil
+}
+
+// partialResult keeps the subjects that were fetched unless mode is
+// FailFast, passing the failure on as a warning.
+func (client *Client) partialResult(mode FetchMode, subjects []string, fetchErr error) (fetched []string, err error) {
+	result, ok := fetchErr.(*FetchError)
+	if !ok || mode == FailFast || len(result.Fetched) == 0 {
+		return subjects, fetchErr
+	}
+
+	// keep what was fetched
+	client.warn(result)
+	return result.Fetched, nil
+}
+
+func subjectsMatching(opts SearchOptions, departments []*regexp.Regexp) (subjects []string) {
+	subjects = make([]string, 0, len(opts.Subjects))
+	for subject := range opts.Subjects {
+		if len(departments) == 0 || matchAny(departments, strings.ToLower(subject)) {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+
+	return subjects
+}
+
+/* RequestError Receivers */
+func (err *RequestError) Error() string {
+	return "requesting " + err.URL + ": " + err.Err.Error()
+}
+
+func (err *RequestError) Unwrap() error {
+	return err.Err
+}
+
+/* FetchError Receivers */
+func (result *FetchError) Error() string {
+	return result.Summary()
+}
+
+func (result *FetchError) Summary() string {
+	summary := fmt.Sprintf("fetched %d/%d subjects", len(result.Fetched), result.Total)
+
+	// sort so output is stable between runs
+	sort.Slice(result.Failed, func(i, j int) bool {
+		return result.Failed[i].Subject < result.Failed[j].Subject
+	})
+
+	if len(result.Failed) > 0 {
+		failed := make([]string, 0, len(result.Failed))
+		for _, subjectErr := range result.Failed {
+			failed = append(failed, subjectErr.Error())
+		}
+		summary += "; failed: " + strings.Join(failed, ", ")
+	}
+
+	if len(result.Skipped) > 0 {
+		summary += fmt.Sprintf("; skipped %d", len(result.Skipped))
+	}
+
+	return summary
+}
+
+/* SubjectError Receivers */
+func (subjectErr SubjectError) Error() string {
+	return subjectErr.Subject + " (" + subjectErr.Err.Error() + ")"
+}
+
+/* Doc Parsers */
+func GetClasses(doc *html.Node, CRNs []int) (classes ClassList, err error) {
+	// get table node
+	tableNode := attr.GetElementById(doc, "resulttable")
+	if tableNode == nil {
+		return ClassList{}, ErrNodeNotFound
+	}
+
+	// rows are usually in tbody, but may be direct children
+	startNode := tableNode.FirstChild
+	for child := tableNode.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "tbody" {
+			startNode = child.FirstChild
+			break
+		}
+	}
+
+	// loop through table rows and add classes
+	classes.Init()
+	for row, rowNum := startNode, 0; row != nil; row = row.NextSibling {
+		// skip text elements and header rows
+		if row.Type != html.ElementNode || row.Data != "tr" {
+			continue
+		}
+		rowNum++
+
+		// collect data cells
+		columns := make([]*html.Node, 0, 14)
+		for column := row.FirstChild; column != nil; column = column.NextSibling {
+			if column.Type == html.ElementNode && column.Data == "td" {
+				columns = append(columns, column)
+			}
+		}
+
+		// skip header and message rows, e.g. when nothing matched
+		if len(columns) <= 7 {
+			log.Println("Skipping row", rowNum, "with", len(columns), "cells")
+			continue
+		}
+
+		// extract data based on table column index
+		var class Class
+		for i, column := range columns {
+			text := nodeText(column)
+
+			switch i {
+			case 0:
+				class.Section = text
+			case 1:
+				class.Title = text
+			case 2:
+				class.Credits = text
+			case 4:
+				class.Max, err = parseCount(text)
+			case 5:
+				class.Open, err = parseCount(text)
+			case 7:
+				class.CRN, err = strconv.Atoi(text)
+			case 9:
+				class.Instructor = instructorText(column)
+			case 10:
+				class.Time = text
+			case 11:
+				class.Begin = text
+			case 12:
+				class.End = text
+			case 13:
+				class.Location = text
+			}
+
+			// check if int conversion problem
+			if err != nil {
+				return classes, fmt.Errorf("row %d column %d: %v", rowNum, i+1, err)
+			}
+		}
+
+		// add created and filled class
+		if CRNs == nil || containsCRN(CRNs, class.CRN) {
+			classes.Add(class)
+		}
+	}
+
+	return classes, nil
+}
+
+func GetFields(doc *html.Node, name string) (fields map[string]string, err error) {
+	// get select node with appropriate name
+	selectNode := attr.GetElementByName(doc, name)
+
+	if selectNode == nil {
+		return nil, ErrNodeNotFound
+	}
+
+	for curr := selectNode.FirstChild; curr != nil; curr = curr.NextSibling {
+		// skip text nodes
+		if curr.Type != html.ElementNode {
+			continue
+		}
+
+		// loop through attributes to find value
+		for _, attribute := range curr.Attr {
+			if attribute.Key == "value" {
+				// only init if there are elements to add
+				if fields == nil {
+					fields = make(map[string]string)
+				}
+
+				// add element
+				fields[attribute.Val] = nodeText(curr)
+				break
+			}
+		}
+
+	}
+
+	if fields != nil {
+		return fields, nil
+	} else {
+		return nil, ErrFieldNotFound
+	}
+}
+
+func GetOptions(doc *html.Node) (opts SearchOptions, err error) {
+	// maps container names to corresponding struct field
+	categories := map[string]*map[string]string{"TERM": &opts.Terms,
+		"DIVS":   &opts.Divisions,
+		"CAMPUS": &opts.Campuses,
+		"SUBJ":   &opts.Subjects,
+		"ATTR":   &opts.Attributes,
+		"CREDIT": &opts.Credits,
+	}
+
+	// concurrency vars
+	errChan := make(chan error, len(categories))
+	wg := &sync.WaitGroup{}
+	lock := &sync.Mutex{}
+
+	// run searches in parallel
+	for cat, field := range categories {
+		wg.Add(1)
+		errChan <- parallelFill(doc, cat, field, lock, wg)
+	}
+	wg.Wait()
+	close(errChan)
+
+	// fill first returned error if any category failed
+	for catErr := range errChan {
+		if catErr != nil && err == nil {
+			err = catErr
+		}
+	}
+
+	return
+}
+
+func parallelFill(doc *html.Node, name string, field *map[string]string, lock *sync.Mutex, wg *sync.WaitGroup) error {
+	defer wg.Done()
+
+	// store copy of location rather than waiting with lock
+	mapping, err := GetFields(doc, name)
+
+	if err != nil {
+		return err
+	} else {
+		// fill in values from mapping
+		lock.Lock()
+		*field = make(map[string]string)
+		for key, val := range mapping {
+			(*field)[key] = val
+		}
+		lock.Unlock()
+	}
+
+	return nil
+}
+
+/* Node Helpers */
+func nodeText(node *html.Node) string {
+	// join all text below node
+	parts := make([]string, 0, 2)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			if text := strings.TrimSpace(node.Data); text != "" {
+				parts = append(parts, text)
+			}
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(node)
+
+	return strings.Join(parts, " ")
+}
+
+func instructorText(column *html.Node) string {
+	// each instructor is linked, TBA is just text
+	names := make([]string, 0, 2)
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "a" {
+			if name := nodeText(node); name != "" {
+				names = append(names, name)
+			}
+			return
+		}
+
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(column)
+
+	if len(names) == 0 {
+		return nodeText(column)
+	}
+
+	return strings.Join(names, "; ")
+}
+
+func parseCount(text string) (count int, err error) {
+	// blank seat counts mean none
+	if text == "" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(strings.Replace(text, ",", "", -1))
+}
+
+func containsCRN(CRNs []int, CRN int) bool {
+	for _, other := range CRNs {
+		if other == CRN {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package classsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"golang.org/x/net/html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden files with current results")
+
+// parseResult is what a page parsed to, compared with a golden file.
+type parseResult struct {
+	Classes *ClassList     `json:",omitempty"`
+	Options *SearchOptions `json:",omitempty"`
+	Error   string         `json:",omitempty"`
+}
+
+func TestGetClasses(t *testing.T) {
+	pages := []string{
+		"classes",     // seats with thousands separators
+		"tba",         // no instructor, time or room yet
+		"instructors", // team taught section
+		"blankseats",  // blank counts mean none
+		"empty",       // nothing matched search
+		"noresults",   // missing resulttable
+		"malformed",   // seat count that isn't a number
+	}
+
+	for _, page := range pages {
+		t.Run(page, func(t *testing.T) {
+			classes, err := GetClasses(readPage(t, page), nil)
+
+			result := parseResult{Classes: &classes}
+			if err != nil {
+				result = parseResult{Error: err.Error()}
+			}
+			checkGolden(t, page, result)
+		})
+	}
+}
+
+func TestGetClassesCRNs(t *testing.T) {
+	classes, err := GetClasses(readPage(t, "classes"), []int{13480})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(classes.List) != 1 || classes.List[0].CRN != 13480 {
+		t.Errorf("got %v, want only CRN 13480", classes.List)
+	}
+}
+
+func TestGetOptions(t *testing.T) {
+	for _, page := range []string{"options", "options_missing"} {
+		t.Run(page, func(t *testing.T) {
+			opts, err := GetOptions(readPage(t, page))
+
+			result := parseResult{Options: &opts}
+			if err != nil {
+				result = parseResult{Error: err.Error()}
+			}
+			checkGolden(t, page, result)
+		})
+	}
+}
+
+func readPage(t *testing.T, name string) *html.Node {
+	t.Helper()
+
+	page, err := ioutil.ReadFile(filepath.Join("testdata", name+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return doc
+}
+
+// checkGolden compares result with testdata/name.golden.json, rewriting it
+// instead with -update.
+func checkGolden(t *testing.T, name string, result parseResult) {
+	t.Helper()
+
+	got, err := json.MarshalIndent(result, "", "\t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	filename := filepath.Join("testdata", name+".golden.json")
+	if *updateGolden {
+		err = ioutil.WriteFile(filename, got, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		t.Fatalf("missing %s, create it with -update", filename)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from %s:\n%s", name, filename, got)
+	}
+}
@@ -0,0 +1,306 @@
+package classsearch
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type Eligibility int
+
+const (
+	EligibleUnknown Eligibility = iota
+	EligiblePass
+	EligibleFail
+)
+
+var (
+	ErrBadRequisite = errors.New("Could not parse requisite expression")
+
+	// tokens in a prerequisite expression
+	requisiteTokens = regexp.MustCompile(`(?i)\(|\)|\band\b|\bor\b|\b[A-Z]{2,4}\s*\d{3,5}\b|minimum grade of [A-F][+-]?|\S+`)
+	gradeToken      = regexp.MustCompile(`(?i)^minimum grade of ([A-F][+-]?)$`)
+	courseToken     = regexp.MustCompile(`(?i)^[A-Z]{2,4}\s*\d{3,5}$`)
+	shortGrade      = regexp.MustCompile(`(?i)(\d{3,5})\s*\(([A-F][+-]?)\)`)
+	fillerWords     = regexp.MustCompile(`(?i)\b(with a|(under)?graduate level)\b`)
+
+	// restriction clause, e.g. "Must be enrolled in one of the following Majors: ..."
+	restrictionClause = regexp.MustCompile(`(?i)^(must|must not|may not|cannot) be (?:enrolled in )?(?:one of )?(?:the following )?(majors?|colleges?|classes|class|levels?)\s*:?\s*(.*)$`)
+	restrictionValues = regexp.MustCompile(`[,;]|\s{2,}`)
+	restrictionPunct  = regexp.MustCompile(`[^a-z0-9&]+`)
+)
+
+type EligibilityResult struct {
+	Status Eligibility
+	Reason string
+}
+
+type requisite interface {
+	evaluate(transcript Transcript) (result EligibilityResult)
+}
+
+type courseRequisite struct {
+	Course   string
+	MinGrade string
+}
+
+type logicRequisite struct {
+	IsAnd bool
+	Terms []requisite
+}
+
+type requisiteParser struct {
+	tokens []string
+	pos    int
+}
+
+/* Eligibility Receivers */
+func (status Eligibility) String() string {
+	switch status {
+	case EligiblePass:
+		return "pass"
+	case EligibleFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+/* Class Receivers */
+func (class Class) CheckEligibility(transcript Transcript) (result EligibilityResult) {
+	if class.Detail == nil {
+		return EligibilityResult{EligibleUnknown, "section details not fetched"}
+	}
+
+	results := make([]EligibilityResult, 0, 5)
+
+	// check prerequisites
+	if prereqs := strings.TrimSpace(class.Detail.Prerequisites); prereqs != "" {
+		req, err := ParseRequisites(prereqs)
+		if err != nil {
+			results = append(results, EligibilityResult{EligibleUnknown, "unparsed prerequisites: " + prereqs})
+		} else {
+			results = append(results, req.evaluate(transcript))
+		}
+	}
+
+	// check restrictions
+	for _, clause := range class.Detail.Restrictions {
+		results = append(results, checkRestriction(clause, transcript))
+	}
+
+	if len(results) == 0 {
+		return EligibilityResult{EligiblePass, "no prerequisites or restrictions"}
+	}
+
+	return combine(true, results)
+}
+
+/* Requisite Parsing */
+func ParseRequisites(expr string) (req requisite, err error) {
+	// turn "(C-)" style grades into full phrases so they tokenize together
+	expr = shortGrade.ReplaceAllString(expr, "$1 minimum grade of $2")
+	expr = fillerWords.ReplaceAllString(expr, " ")
+
+	parser := requisiteParser{tokens: requisiteTokens.FindAllString(expr, -1)}
+	if len(parser.tokens) == 0 {
+		return nil, ErrBadRequisite
+	}
+
+	req, err = parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	// check that entire expression was consumed
+	if parser.pos != len(parser.tokens) {
+		return nil, ErrBadRequisite
+	}
+
+	return req, nil
+}
+
+func (parser *requisiteParser) peek() string {
+	if parser.pos >= len(parser.tokens) {
+		return ""
+	}
+	return strings.ToLower(parser.tokens[parser.pos])
+}
+
+func (parser *requisiteParser) parseOr() (req requisite, err error) {
+	return parser.parseLogic(false, parser.parseAnd)
+}
+
+func (parser *requisiteParser) parseAnd() (req requisite, err error) {
+	return parser.parseLogic(true, parser.parseFactor)
+}
+
+func (parser *requisiteParser) parseLogic(isAnd bool, next func() (requisite, error)) (req requisite, err error) {
+	op := "or"
+	if isAnd {
+		op = "and"
+	}
+
+	logic := logicRequisite{IsAnd: isAnd}
+	for {
+		term, err := next()
+		if err != nil {
+			return nil, err
+		}
+		logic.Terms = append(logic.Terms, term)
+
+		if parser.peek() != op {
+			break
+		}
+		parser.pos++
+	}
+
+	// collapse single terms
+	if len(logic.Terms) == 1 {
+		return logic.Terms[0], nil
+	}
+
+	return logic, nil
+}
+
+func (parser *requisiteParser) parseFactor() (req requisite, err error) {
+	token := parser.peek()
+
+	// nested expression
+	if token == "(" {
+		parser.pos++
+		req, err = parser.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if parser.peek() != ")" {
+			return nil, ErrBadRequisite
+		}
+		parser.pos++
+
+		return req, nil
+	}
+
+	// course with optional minimum grade
+	if courseToken.MatchString(token) {
+		parser.pos++
+		course := courseRequisite{Course: NormalizeCourse(token)}
+
+		if match := gradeToken.FindStringSubmatch(parser.peek()); match != nil {
+			course.MinGrade = strings.ToUpper(match[1])
+			parser.pos++
+		}
+
+		return course, nil
+	}
+
+	return nil, ErrBadRequisite
+}
+
+/* Requisite Evaluation */
+func (req courseRequisite) evaluate(transcript Transcript) (result EligibilityResult) {
+	completed, ok := transcript.Find(req.Course)
+
+	switch {
+	case !ok:
+		return EligibilityResult{EligibleFail, req.Course + " not taken"}
+	case completed.InProgress():
+		return EligibilityResult{EligibleUnknown, req.Course + " in progress"}
+	case req.MinGrade != "" && !completed.Meets(req.MinGrade):
+		return EligibilityResult{EligibleFail, fmt.Sprintf("%s grade %s below %s", req.Course, completed.Grade, req.MinGrade)}
+	case !completed.Passed():
+		return EligibilityResult{EligibleFail, fmt.Sprintf("%s not passed (%s)", req.Course, completed.Grade)}
+	}
+
+	return EligibilityResult{EligiblePass, req.Course + " completed"}
+}
+
+func (req logicRequisite) evaluate(transcript Transcript) (result EligibilityResult) {
+	results := make([]EligibilityResult, 0, len(req.Terms))
+	for _, term := range req.Terms {
+		results = append(results, term.evaluate(transcript))
+	}
+
+	return combine(req.IsAnd, results)
+}
+
+func combine(isAnd bool, results []EligibilityResult) (result EligibilityResult) {
+	// status that decides the expression on its own
+	deciding := EligiblePass
+	if isAnd {
+		deciding = EligibleFail
+	}
+
+	reasons := make([]string, 0, len(results))
+	status := EligiblePass
+	if !isAnd {
+		status = EligibleFail
+	}
+
+	// return first deciding result, otherwise collect reasons
+	for _, result := range results {
+		if result.Status == deciding {
+			return result
+		}
+
+		if result.Status == EligibleUnknown {
+			status = EligibleUnknown
+		}
+		reasons = append(reasons, result.Reason)
+	}
+
+	return EligibilityResult{status, strings.Join(reasons, "; ")}
+}
+
+func checkRestriction(clause string, transcript Transcript) (result EligibilityResult) {
+	match := restrictionClause.FindStringSubmatch(strings.TrimSpace(clause))
+	if match == nil {
+		return EligibilityResult{EligibleUnknown, "unparsed restriction: " + clause}
+	}
+
+	mustBe := strings.ToLower(match[1]) == "must"
+	category := strings.ToLower(match[2])
+
+	// get transcript field for category
+	var field string
+	switch {
+	case strings.HasPrefix(category, "major"):
+		field = transcript.Major
+	case strings.HasPrefix(category, "college"):
+		field = transcript.College
+	case strings.HasPrefix(category, "class"):
+		field = transcript.Standing
+	case strings.HasPrefix(category, "level"):
+		field = transcript.Level
+	}
+
+	if field == "" {
+		return EligibilityResult{EligibleUnknown, "transcript missing " + category + " for: " + clause}
+	}
+
+	// check if field is one of the listed values, so Graduate doesn't
+	// match Undergraduate
+	listed := false
+	field = normalizeRestriction(field)
+	for _, value := range restrictionValues.Split(match[3], -1) {
+		value = normalizeRestriction(value)
+		if value != "" && value == field {
+			listed = true
+			break
+		}
+	}
+
+	if listed == mustBe {
+		return EligibilityResult{EligiblePass, clause}
+	}
+
+	return EligibilityResult{EligibleFail, clause}
+}
+
+// normalizeRestriction lowercases a restriction value and collapses
+// punctuation and spacing.
+func normalizeRestriction(value string) (normalized string) {
+	return strings.Join(strings.Fields(restrictionPunct.ReplaceAllString(strings.ToLower(value), " ")), " ")
+}
@@ -0,0 +1,29 @@
+package classsearch
+
+import (
+	"testing"
+)
+
+func TestCheckRestriction(t *testing.T) {
+	transcript := Transcript{Standing: "Junior", Level: "Undergraduate", Major: "Computer Science", College: "Engineering"}
+
+	tests := []struct {
+		clause string
+		want   Eligibility
+	}{
+		{"Must be enrolled in one of the following Majors: Computer Science, Computer Engineering", EligiblePass},
+		{"Must be enrolled in one of the following Majors: computer science.", EligiblePass},
+		{"Must be enrolled in one of the following Majors: Science", EligibleFail},
+		{"Must be enrolled in one of the following Levels: Graduate", EligibleFail},
+		{"Must not be enrolled in one of the following Levels: Graduate", EligiblePass},
+		{"Must be enrolled in one of the following Classes: Junior; Senior", EligiblePass},
+		{"Must be enrolled in one of the following Colleges: Arts & Letters", EligibleFail},
+	}
+
+	for _, test := range tests {
+		result := checkRestriction(test.clause, transcript)
+		if result.Status != test.want {
+			t.Errorf("%q got %v, want %v", test.clause, result.Status, test.want)
+		}
+	}
+}
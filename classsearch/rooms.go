@@ -0,0 +1,125 @@
+package classsearch
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// room numbers contain at least one digit, e.g. 129 or B01
+	roomNumber = regexp.MustCompile(`^[A-Za-z]?\d+[A-Za-z]?$`)
+)
+
+type Room struct {
+	Building string
+	Number   string
+}
+
+type RoomSlot struct {
+	Day   time.Weekday
+	Start int
+	End   int
+	Class Class
+}
+
+type RoomSchedule struct {
+	Room  Room
+	Slots []RoomSlot
+}
+
+/* Room Functions */
+func ParseLocation(location string) (room Room, ok bool) {
+	fields := strings.Fields(location)
+
+	// skip unassigned locations
+	if len(fields) == 0 || strings.EqualFold(location, "TBA") {
+		return room, false
+	}
+
+	// split trailing room number from building
+	last := len(fields) - 1
+	if last > 0 && roomNumber.MatchString(fields[last]) {
+		return Room{Building: strings.Join(fields[:last], " "), Number: fields[last]}, true
+	}
+
+	return Room{Building: strings.Join(fields, " ")}, true
+}
+
+/* Room Receivers */
+func (room Room) String() string {
+	return strings.TrimSpace(room.Building + " " + room.Number)
+}
+
+/* RoomSchedule Receivers */
+func (schedule RoomSchedule) IsFree(day time.Weekday, start, end int) bool {
+	for _, slot := range schedule.Slots {
+		if slot.Day == day && slot.Start < end && start < slot.End {
+			return false
+		}
+	}
+	return true
+}
+
+/* ClassList Receivers */
+func (classes ClassList) ByRoom(rooms []*regexp.Regexp) (schedules []RoomSchedule) {
+	groups := make(map[string]*RoomSchedule)
+
+	// add each meeting of each class to its room
+	for _, class := range classes.List {
+		room, ok := ParseLocation(class.Location)
+		if !ok {
+			continue
+		}
+
+		key := strings.ToLower(room.String())
+		if len(rooms) > 0 && !matchAny(rooms, key) {
+			continue
+		}
+
+		schedule, ok := groups[key]
+		if !ok {
+			schedule = &RoomSchedule{Room: room}
+			groups[key] = schedule
+		}
+
+		for _, meeting := range class.Meetings() {
+			for _, day := range meeting.Days {
+				schedule.Slots = append(schedule.Slots, RoomSlot{day, meeting.Start, meeting.End, class})
+			}
+		}
+	}
+
+	// sort rooms by name with slots in weekly order
+	schedules = make([]RoomSchedule, 0, len(groups))
+	for _, schedule := range groups {
+		sort.Slice(schedule.Slots, func(i, j int) bool {
+			if schedule.Slots[i].Day != schedule.Slots[j].Day {
+				return weekOrder(schedule.Slots[i].Day) < weekOrder(schedule.Slots[j].Day)
+			}
+			return schedule.Slots[i].Start < schedule.Slots[j].Start
+		})
+		schedules = append(schedules, *schedule)
+	}
+
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Room.String() < schedules[j].Room.String()
+	})
+
+	return schedules
+}
+
+func weekOrder(day time.Weekday) int {
+	// start week on monday
+	return (int(day) + 6) % 7
+}
+
+func matchAny(exprs []*regexp.Regexp, str string) bool {
+	for _, expr := range exprs {
+		if expr.MatchString(str) {
+			return true
+		}
+	}
+	return false
+}
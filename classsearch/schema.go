@@ -0,0 +1,151 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"regexp"
+)
+
+const (
+	// bump when stored cache data changes shape and add a migration below
+	CacheVersion = 2
+)
+
+var (
+	ErrCacheTooNew = errors.New("Cache was written by a newer version of cscli (upgrade cscli or use another --directory)")
+	ErrBadRecord   = errors.New("Cache record has unexpected format")
+
+	// CacheMigrations[name][i] upgrades record name from version i to i+1
+	CacheMigrations = map[string][]Migration{
+		ClassCacheFilename:   {addSubjectTimestamps, markSubjectsStale},
+		OptionsCacheFilename: {noMigration, noMigration},
+	}
+)
+
+type Migration func(record map[string]interface{}) (err error)
+
+/* Schema Functions */
+func readRecord(backend Backend, name string) (blob []byte, err error) {
+	blob, err = backend.ReadRecord(name)
+	if err != nil {
+		return
+	}
+
+	// bring old records up to date
+	blob, changed, err := UpgradeRecord(name, blob)
+	if err != nil {
+		return
+	}
+
+	// save upgraded record in place
+	if changed {
+		log.Println("Writing upgraded record", name)
+		err = backend.WriteRecord(name, blob)
+		if err != nil {
+			return
+		}
+	}
+
+	return blob, nil
+}
+
+func UpgradeRecord(name string, blob []byte) (upgraded []byte, changed bool, err error) {
+	var record map[string]interface{}
+	err = json.Unmarshal(blob, &record)
+	if err != nil {
+		return
+	}
+
+	// records without a version predate versioning
+	version := 0
+	if value, ok := record["Version"].(float64); ok {
+		version = int(value)
+	}
+
+	if version > CacheVersion {
+		return nil, false, ErrCacheTooNew
+	}
+
+	if version == CacheVersion {
+		return blob, false, nil
+	}
+
+	// apply each migration in order
+	migrations := CacheMigrations[name]
+	for ; version < CacheVersion; version++ {
+		log.Println("Migrating", name, "from version", version, "to", version+1)
+
+		if version < len(migrations) {
+			err = migrations[version](record)
+			if err != nil {
+				return
+			}
+		}
+	}
+	record["Version"] = CacheVersion
+
+	upgraded, err = json.Marshal(record)
+	return upgraded, true, err
+}
+
+/* Migrations */
+func noMigration(record map[string]interface{}) (err error) {
+	return nil
+}
+
+// version 0 -> 1: track staleness per subject using whole cache timestamp
+func addSubjectTimestamps(record map[string]interface{}) (err error) {
+	if subjects, ok := record["Subjects"].(map[string]interface{}); ok && len(subjects) > 0 {
+		return nil
+	}
+
+	info, ok := record["Info"].(map[string]interface{})
+	if !ok {
+		return ErrBadRecord
+	}
+
+	// get subjects of stored classes
+	subjects := make(map[string]interface{})
+	subjectPattern := regexp.MustCompile("^[A-Z]+")
+	if classes, ok := record["Classes"].(map[string]interface{}); ok {
+		list, _ := classes["List"].([]interface{})
+		for _, class := range list {
+			fields, ok := class.(map[string]interface{})
+			if !ok {
+				return ErrBadRecord
+			}
+
+			section, _ := fields["Section"].(string)
+			if subject := subjectPattern.FindString(section); subject != "" {
+				subjects[subject] = map[string]interface{}{"Timestamp": info["Timestamp"], "Rate": info["Rate"]}
+			}
+		}
+	}
+
+	record["Subjects"] = subjects
+	return nil
+}
+
+// version 1 -> 2: classes gained attributes, refetch every subject to fill
+// them but keep timestamps so stored classes are still served if that fails
+func markSubjectsStale(record map[string]interface{}) (err error) {
+	subjects, ok := record["Subjects"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	for subject, value := range subjects {
+		info, ok := value.(map[string]interface{})
+		if !ok {
+			return ErrBadRecord
+		}
+
+		info["Stale"] = true
+		subjects[subject] = info
+	}
+
+	// attributes are refetched along with classes
+	delete(record, "Tags")
+	return nil
+}
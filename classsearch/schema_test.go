@@ -0,0 +1,110 @@
+package classsearch
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// v1Timestamp is when every subject in testdata/v1 was fetched.
+var v1Timestamp = time.Date(2019, 8, 1, 12, 0, 0, 0, time.UTC)
+
+func TestUpgradeFailedRefresh(t *testing.T) {
+	client := v1Client(t)
+
+	// every fetch fails without recorded pages
+	empty := tempDir(t)
+	defer os.RemoveAll(empty)
+	client.Fixtures = FixtureDir{Directory: empty, Replay: true}
+
+	info := FilterInfo{Departments: []*regexp.Regexp{regexp.MustCompile("^cse$")}}
+	classes, err := client.Search(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(classes.List) != 2 {
+		t.Errorf("got %d CSE classes, want 2 stored ones", len(classes.List))
+	}
+
+	subject := client.Cache.Subjects["CSE"]
+	if !subject.Timestamp.Equal(v1Timestamp) || !subject.Stale {
+		t.Errorf("got CSE fetched %v with stale %v, want %v and stale", subject.Timestamp, subject.Stale, v1Timestamp)
+	}
+
+	// stored classes are served with their age
+	found := false
+	for _, warning := range client.Warnings() {
+		var stale *StaleWarning
+		if errors.As(warning, &stale) && stale.What == "classes for CSE" {
+			found = true
+			if !stale.Timestamp.Equal(v1Timestamp) {
+				t.Errorf("warned CSE fetched %v, want %v", stale.Timestamp, v1Timestamp)
+			}
+		}
+	}
+
+	if !found {
+		t.Error("no stale warning for CSE")
+	}
+}
+
+func TestUpgradeRefetch(t *testing.T) {
+	client := v1Client(t)
+	client.Fixtures = FixtureDir{Directory: "testdata/fixtures", Replay: true}
+
+	info := FilterInfo{Departments: []*regexp.Regexp{regexp.MustCompile("^cse$")}}
+	classes, err := client.Search(context.Background(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stale subjects are refetched even though they are within their rate
+	checkReplayClasses(t, classes, "CSE")
+	if client.Cache.Subjects["CSE"].Stale {
+		t.Error("CSE still stale after refetch")
+	}
+}
+
+// v1Client caches in a copy of the version 1 store in testdata/v1, with
+// rates long enough that only the migration makes subjects stale.
+func v1Client(t *testing.T) (client *Client) {
+	t.Helper()
+
+	dir := tempDir(t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, name := range []string{ClassCacheFilename, OptionsCacheFilename} {
+		blob, err := ioutil.ReadFile(filepath.Join("testdata", "v1", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(filepath.Join(dir, name), blob, 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cache := &ClassCache{}
+	cache.Init()
+	err := cache.SetDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache.SetRates(time.Since(v1Timestamp)+time.Hour, time.Since(v1Timestamp)+time.Hour)
+
+	err = cache.Open("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cache.Close() })
+
+	return NewClient(cache)
+}
@@ -0,0 +1,92 @@
+package classsearch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+const (
+	NDSourceName     = "nd"
+	BannerSourceName = "banner"
+)
+
+var (
+	ErrUnknownSource = errors.New("Unknown source type (use nd or banner)")
+
+	Sources = []string{NDSourceName, BannerSourceName}
+)
+
+// Source is a class search site that classes can be fetched from. Pages are
+// requested through fetcher, which throttles and records them.
+type Source interface {
+	Options(ctx context.Context, fetcher Fetcher, term string) (opts SearchOptions, err error)
+	Search(ctx context.Context, fetcher Fetcher, input FormInput) (classes ClassList, err error)
+	Section(ctx context.Context, fetcher Fetcher, term string, CRN int) (detail SectionDetail, err error)
+}
+
+// Fetcher gets pages for sources, e.g. a Client.
+type Fetcher interface {
+	Fetch(ctx context.Context, url string, args []string) (page []byte, err error)
+}
+
+// NDSource scrapes Notre Dame's class search servlets.
+type NDSource struct {
+	Site string // base URL, e.g. DefaultSite or one started with fake-server
+}
+
+/* Source Functions */
+// OpenSource opens a source of type source at site, keeping any session
+// state in cache directory dir.
+func OpenSource(source string, site string, dir string) (src Source, err error) {
+	log.Println("Using", source, "source at", site)
+
+	switch source {
+	case NDSourceName, "":
+		return NDSource{Site: strings.TrimRight(site, "/")}, nil
+	case BannerSourceName:
+		if site == DefaultSite {
+			return nil, ErrBannerSite
+		}
+		return NewBannerSource(site, dir), nil
+	}
+
+	return nil, ErrUnknownSource
+}
+
+/* NDSource Receivers */
+func (source NDSource) Options(ctx context.Context, fetcher Fetcher, term string) (opts SearchOptions, err error) {
+	doc, err := parseURL(ctx, fetcher, source.site()+searchPath, "--data", "")
+	if err != nil {
+		return
+	}
+
+	return GetOptions(doc)
+}
+
+func (source NDSource) Search(ctx context.Context, fetcher Fetcher, input FormInput) (classes ClassList, err error) {
+	doc, err := parseURL(ctx, fetcher, source.site()+searchPath, "--data", input.String())
+	if err != nil {
+		return
+	}
+
+	return GetClasses(doc, nil)
+}
+
+func (source NDSource) Section(ctx context.Context, fetcher Fetcher, term string, CRN int) (detail SectionDetail, err error) {
+	doc, err := parseURL(ctx, fetcher, fmt.Sprintf("%s%s?TERM=%s&CRN=%d", source.site(), detailPath, term, CRN))
+	if err != nil {
+		return
+	}
+
+	return GetSectionDetail(doc)
+}
+
+func (source NDSource) site() string {
+	if source.Site == "" {
+		return DefaultSite
+	}
+	return source.Site
+}
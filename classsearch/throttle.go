@@ -0,0 +1,148 @@
+package classsearch
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultParallel = 4
+	DefaultRate     = 4.0 // requests per second
+	DefaultJitter   = time.Millisecond * 250
+)
+
+// Throttle bounds requests in flight with a slot per request and spaces
+// them out with a token bucket refilled at Rate tokens per second.
+type Throttle struct {
+	Parallel int
+	Rate     float64 // no rate limit if zero
+	Jitter   time.Duration
+
+	slots  chan bool
+	mutex  sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+/* Throttle Functions */
+func NewThrottle(parallel int, rate float64, jitter time.Duration) (throttle *Throttle) {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	if rate < 0 {
+		rate = 0
+	}
+
+	// allow a burst of one request per slot
+	return &Throttle{
+		Parallel: parallel,
+		Rate:     rate,
+		Jitter:   jitter,
+		slots:    make(chan bool, parallel),
+		tokens:   float64(parallel),
+		last:     time.Now(),
+	}
+}
+
+/* Throttle Receivers */
+func (throttle *Throttle) Acquire(ctx context.Context) (err error) {
+	select {
+	case throttle.slots <- true:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// wait for token then spread requests out a bit
+	timer := time.NewTimer(throttle.reserve() + throttle.jitter())
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		throttle.refund()
+		throttle.Release()
+		return ctx.Err()
+	}
+}
+
+func (throttle *Throttle) Release() {
+	<-throttle.slots
+}
+
+// Workers runs work for indices 0 to count-1 on at most Parallel goroutines.
+func (throttle *Throttle) Workers(count int, work func(i int)) {
+	jobs := make(chan int, count)
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+
+	workers := throttle.Parallel
+	if count < workers {
+		workers = count
+	}
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				work(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (throttle *Throttle) reserve() (wait time.Duration) {
+	if throttle.Rate == 0 {
+		return 0
+	}
+
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	// refill bucket up to burst size
+	now := time.Now()
+	throttle.tokens += now.Sub(throttle.last).Seconds() * throttle.Rate
+	if burst := float64(throttle.Parallel); throttle.tokens > burst {
+		throttle.tokens = burst
+	}
+	throttle.last = now
+
+	// take token, waiting until it is refilled if bucket is empty
+	throttle.tokens--
+	if throttle.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-throttle.tokens / throttle.Rate * float64(time.Second))
+}
+
+// refund gives back the token of a request that was never sent.
+func (throttle *Throttle) refund() {
+	if throttle.Rate == 0 {
+		return
+	}
+
+	throttle.mutex.Lock()
+	defer throttle.mutex.Unlock()
+
+	throttle.tokens++
+	if burst := float64(throttle.Parallel); throttle.tokens > burst {
+		throttle.tokens = burst
+	}
+}
+
+func (throttle *Throttle) jitter() time.Duration {
+	if throttle.Jitter <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(throttle.Jitter)))
+}
@@ -0,0 +1,157 @@
+package classsearch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestThrottleParallel(t *testing.T) {
+	const parallel = 2
+	const requests = 10
+
+	// count requests in flight at once
+	lock := &sync.Mutex{}
+	inflight, peak, served := 0, 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inflight++
+		served++
+		if inflight > peak {
+			peak = inflight
+		}
+		lock.Unlock()
+
+		time.Sleep(time.Millisecond * 50)
+
+		lock.Lock()
+		inflight--
+		lock.Unlock()
+	}))
+	defer server.Close()
+
+	client := NewClient(nil)
+	client.Limits = NewThrottle(parallel, 0, 0)
+
+	// fetch from more goroutines than there are slots
+	wg := &sync.WaitGroup{}
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Fetch(context.Background(), server.URL, nil)
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if served != requests {
+		t.Errorf("served %d requests, want %d", served, requests)
+	}
+
+	if peak > parallel {
+		t.Errorf("peak of %d requests in flight, want at most %d", peak, parallel)
+	}
+}
+
+func TestThrottleSpacing(t *testing.T) {
+	const requests = 10
+
+	tests := []struct {
+		name     string
+		rate     float64
+		jitter   time.Duration
+		min, max time.Duration // total time for all requests
+	}{
+		{"rate", 50, 0, time.Millisecond * 20 * (requests - 1), time.Second},
+		{"jitter", 0, time.Millisecond * 20, time.Millisecond * 20 * requests / 4, time.Second},
+		{"none", 0, 0, 0, time.Millisecond * 50},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			throttle := NewThrottle(1, test.rate, test.jitter)
+
+			start := time.Now()
+			for i := 0; i < requests; i++ {
+				err := throttle.Acquire(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				throttle.Release()
+
+				// after burst of one, each request waits a full token
+				if test.rate > 0 {
+					elapsed := time.Since(start)
+					want := time.Duration(float64(i) / test.rate * float64(time.Second))
+					if elapsed < want-time.Millisecond {
+						t.Errorf("request %d after %v, want at least %v", i, elapsed, want)
+					}
+				}
+			}
+
+			elapsed := time.Since(start)
+			if elapsed < test.min || elapsed > test.max {
+				t.Errorf("%d requests took %v, want between %v and %v", requests, elapsed, test.min, test.max)
+			}
+		})
+	}
+}
+
+func TestThrottleCancel(t *testing.T) {
+	throttle := NewThrottle(1, 0, 0)
+
+	err := throttle.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// no free slot so wait until canceled
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	err = throttle.Acquire(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestThrottleCancelRefund(t *testing.T) {
+	const rate = 10 // a token every 100ms
+
+	throttle := NewThrottle(1, rate, 0)
+
+	// spend the burst
+	err := throttle.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle.Release()
+
+	// give up while waiting on the next token
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	err = throttle.Acquire(ctx)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// the canceled wait gave its token back, so the next one is due a token
+	// after the first rather than two
+	start := time.Now()
+	err = throttle.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttle.Release()
+
+	if elapsed := time.Since(start); elapsed > time.Millisecond*150 {
+		t.Errorf("waited %v after canceled request, want under 150ms", elapsed)
+	}
+}
@@ -0,0 +1,188 @@
+package classsearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	slotMinutes = 30
+	cellWidth   = 14
+	labelWidth  = 7
+
+	colorReset    = "\x1b[0m"
+	colorConflict = "\x1b[41;97m"
+)
+
+var (
+	ErrNoMeetings = errors.New("No scheduled meetings found")
+
+	// background colors assigned to courses in order
+	CoursePalette = []string{"\x1b[42;30m", "\x1b[44;97m", "\x1b[43;30m", "\x1b[45;97m", "\x1b[46;30m", "\x1b[47;30m"}
+
+	GridDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+)
+
+type gridEntry struct {
+	index   int // index of class in timetable
+	meeting Meeting
+}
+
+/* Timetable Functions */
+func RenderTimetable(w io.Writer, classes []Class, color bool) (err error) {
+	// get hour range covering all meetings
+	first, last := 24*60, 0
+	for _, class := range classes {
+		for _, meeting := range class.Meetings() {
+			if meeting.Start < first {
+				first = meeting.Start
+			}
+			if meeting.End > last {
+				last = meeting.End
+			}
+		}
+	}
+
+	if first >= last {
+		return ErrNoMeetings
+	}
+
+	first = first / 60 * 60
+	last = (last + 59) / 60 * 60
+
+	// print header
+	lines := []string{gridBorder("┌", "┬", "┐")}
+	header := "│" + pad("", labelWidth)
+	for _, day := range GridDays {
+		header += "│" + pad(day.String(), cellWidth)
+	}
+	lines = append(lines, header+"│", gridBorder("├", "┼", "┤"))
+
+	// print rows for each slot of the day
+	for slot := first; slot < last; slot += slotMinutes {
+		label := ""
+		if slot%60 == 0 {
+			label = FormatClock(slot)
+		}
+
+		row := "│" + pad(label, labelWidth)
+		for _, day := range GridDays {
+			row += "│" + gridCell(classes, day, slot, color)
+		}
+		lines = append(lines, row+"│")
+	}
+	lines = append(lines, gridBorder("└", "┴", "┘"))
+
+	// print legend and conflicts
+	lines = append(lines, "")
+	for i, class := range classes {
+		key := fmt.Sprintf("%d", class.CRN)
+		if color {
+			key = CoursePalette[i%len(CoursePalette)] + key + colorReset
+		}
+
+		lines = append(lines, strings.Join([]string{key, class.Section, class.Title, class.Time}, "\t"))
+	}
+
+	for i, class := range classes {
+		for _, other := range classes[i+1:] {
+			if classesOverlap(class, other) {
+				conflict := fmt.Sprintf("CONFLICT: %s (%d) overlaps %s (%d)", class.Section, class.CRN, other.Section, other.CRN)
+				if color {
+					conflict = colorConflict + conflict + colorReset
+				}
+				lines = append(lines, conflict)
+			}
+		}
+	}
+
+	for _, line := range lines {
+		_, err = fmt.Fprintln(w, line)
+		if err != nil {
+			return
+		}
+	}
+
+	return nil
+}
+
+func gridCell(classes []Class, day time.Weekday, slot int, color bool) (cell string) {
+	// find meetings occupying slot
+	entries := make([]gridEntry, 0, 2)
+	for i, class := range classes {
+		for _, meeting := range class.Meetings() {
+			if meeting.MeetsOn(day) && meeting.Start < slot+slotMinutes && slot < meeting.End {
+				entries = append(entries, gridEntry{i, meeting})
+			}
+		}
+	}
+
+	switch len(entries) {
+	case 0:
+		return pad("", cellWidth)
+	case 1:
+		entry := entries[0]
+		text := ""
+
+		// label first two slots of meeting
+		switch {
+		case entry.meeting.Start >= slot:
+			text = courseLabel(classes[entry.index])
+		case entry.meeting.Start >= slot-slotMinutes:
+			text = FormatClock(entry.meeting.Start) + "-" + FormatClock(entry.meeting.End)
+		}
+
+		if color {
+			return CoursePalette[entry.index%len(CoursePalette)] + pad(text, cellWidth) + colorReset
+		}
+		return pad(text, cellWidth)
+	}
+
+	// mark overlapping courses
+	labels := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		labels = append(labels, courseLabel(classes[entry.index]))
+	}
+
+	text := "!" + strings.Join(labels, "/")
+	if color {
+		return colorConflict + pad(text, cellWidth) + colorReset
+	}
+	return pad(text, cellWidth)
+}
+
+func classesOverlap(class, other Class) bool {
+	for _, meeting := range class.Meetings() {
+		for _, otherMeeting := range other.Meetings() {
+			if meeting.Overlaps(otherMeeting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func courseLabel(class Class) string {
+	// drop section number, e.g. "CSE 30341 - 01"
+	return strings.TrimSpace(strings.SplitN(class.Section, " - ", 2)[0])
+}
+
+func gridBorder(left, middle, right string) string {
+	border := left + strings.Repeat("─", labelWidth)
+	for range GridDays {
+		border += middle + strings.Repeat("─", cellWidth)
+	}
+	return border + right
+}
+
+func pad(text string, width int) string {
+	// truncate or fill to width in runes
+	if utf8.RuneCountInString(text) > width {
+		return string([]rune(text)[:width])
+	}
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+}
@@ -0,0 +1,127 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrNoTranscript = errors.New("Transcript file not found")
+
+	// grade points used to compare against minimum grades
+	GradePoints = map[string]int{
+		"A": 12, "A-": 11,
+		"B+": 10, "B": 9, "B-": 8,
+		"C+": 7, "C": 6, "C-": 5,
+		"D+": 4, "D": 3, "D-": 2,
+		"F": 0,
+	}
+
+	// grades that complete a course without grade points
+	PassGrades = map[string]bool{"P": true, "S": true, "CR": true}
+
+	courseSpacing = regexp.MustCompile(`^([A-Z]+)\s*(\d+)`)
+)
+
+type Transcript struct {
+	Standing string // Freshman, Sophomore, Junior, Senior
+	Level    string // Undergraduate, Graduate
+	Major    string
+	College  string
+	Courses  []CompletedCourse
+}
+
+type CompletedCourse struct {
+	Course string // e.g. CSE 20312
+	Grade  string // empty or IP while in progress
+}
+
+/* Transcript Functions */
+func LoadTranscript(filename string) (transcript Transcript, err error) {
+	// read in from file
+	blob, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return transcript, ErrNoTranscript
+	} else if err != nil {
+		return transcript, fmt.Errorf("reading transcript: %w", err)
+	}
+
+	// parse JSON into fields
+	err = json.Unmarshal(blob, &transcript)
+	if err != nil {
+		return
+	}
+
+	return transcript, nil
+}
+
+func NormalizeCourse(course string) (normalized string) {
+	course = strings.ToUpper(strings.TrimSpace(course))
+
+	// force single space between subject and number
+	if match := courseSpacing.FindStringSubmatch(course); match != nil {
+		return match[1] + " " + match[2]
+	}
+
+	return course
+}
+
+/* Transcript Receivers */
+func (transcript Transcript) Find(course string) (completed CompletedCourse, ok bool) {
+	course = NormalizeCourse(course)
+
+	// prefer the best attempt at a course
+	for _, attempt := range transcript.Courses {
+		if NormalizeCourse(attempt.Course) != course {
+			continue
+		}
+
+		if !ok || attempt.betterThan(completed) {
+			completed, ok = attempt, true
+		}
+	}
+
+	return completed, ok
+}
+
+/* CompletedCourse Receivers */
+func (course CompletedCourse) InProgress() bool {
+	grade := strings.ToUpper(strings.TrimSpace(course.Grade))
+	return grade == "" || grade == "IP"
+}
+
+func (course CompletedCourse) Passed() bool {
+	grade := strings.ToUpper(strings.TrimSpace(course.Grade))
+	points, ok := GradePoints[grade]
+	return PassGrades[grade] || (ok && points > 0)
+}
+
+func (course CompletedCourse) Meets(minimum string) bool {
+	grade := strings.ToUpper(strings.TrimSpace(course.Grade))
+
+	// pass/fail grades satisfy any minimum
+	if PassGrades[grade] {
+		return true
+	}
+
+	points, ok := GradePoints[grade]
+	minPoints, minOk := GradePoints[strings.ToUpper(minimum)]
+	if !minOk {
+		return course.Passed()
+	}
+
+	return ok && points >= minPoints
+}
+
+func (course CompletedCourse) betterThan(other CompletedCourse) bool {
+	if course.Passed() != other.Passed() {
+		return course.Passed()
+	}
+
+	return GradePoints[strings.ToUpper(course.Grade)] > GradePoints[strings.ToUpper(other.Grade)]
+}
@@ -0,0 +1,176 @@
+package classsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrNoTargets = errors.New("Nothing to watch (give CRNs, --course or --watch)")
+)
+
+// WatchTarget is a course or set of CRNs whose sections are watched. It is
+// resolved against the class list on every poll, so sections added after
+// watching started are included.
+type WatchTarget struct {
+	Label  string     // names target in snapshots, defaults to course or CRNs
+	Course string     // e.g. CSE 30341, every section is watched if set
+	Filter FilterInfo // further restricts sections, e.g. by professor or CRN
+	Rules  []AlertRule
+}
+
+// watchSpec is a target in a watch file, e.g.
+// {"course": "CSE 30341", "professor": ["smith"], "rules": ["open>=3:text"]}
+type watchSpec struct {
+	Name      string
+	Course    string
+	CRNs      []int
+	Professor []string
+	After     string
+	Before    string
+	Rules     []string
+}
+
+/* WatchTarget Functions */
+func CourseTarget(course string, filter FilterInfo) (target WatchTarget) {
+	return WatchTarget{Course: NormalizeCourse(course), Filter: filter}
+}
+
+func CRNTarget(CRN int) (target WatchTarget) {
+	return WatchTarget{Filter: FilterInfo{CRNs: []int{CRN}}}
+}
+
+// LoadWatchFile reads targets from a json list of watch specs.
+func LoadWatchFile(filename string) (targets []WatchTarget, err error) {
+	blob, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+
+	var specs []watchSpec
+	err = json.Unmarshal(blob, &specs)
+	if err != nil {
+		return
+	}
+
+	for i, spec := range specs {
+		target, err := spec.Target()
+		if err != nil {
+			return targets, fmt.Errorf("watch target %d: %s", i+1, err)
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// watchQuery matches every class any of targets could match.
+func watchQuery(targets []WatchTarget) (query FilterInfo) {
+	for _, target := range targets {
+		departments := target.Query().Departments
+
+		// any target without a subject needs every subject
+		if len(departments) == 0 {
+			return FilterInfo{}
+		}
+		query.Departments = append(query.Departments, departments...)
+	}
+
+	return query
+}
+
+/* watchSpec Receivers */
+func (spec watchSpec) Target() (target WatchTarget, err error) {
+	if spec.Course == "" && len(spec.CRNs) == 0 {
+		return target, ErrNoTargets
+	}
+
+	var filter FilterInfo
+	filter.CRNs = spec.CRNs
+	for _, professor := range spec.Professor {
+		expr, err := regexp.Compile(strings.ToLower(professor))
+		if err != nil {
+			return target, err
+		}
+		filter.Professors = append(filter.Professors, expr)
+	}
+
+	if spec.After != "" {
+		filter.After, err = ParseClock(spec.After)
+		if err != nil {
+			return
+		}
+	}
+
+	if spec.Before != "" {
+		filter.Before, err = ParseClock(spec.Before)
+		if err != nil {
+			return
+		}
+	}
+
+	target = WatchTarget{Label: spec.Name, Filter: filter}
+	if spec.Course != "" {
+		target = CourseTarget(spec.Course, filter)
+		target.Label = spec.Name
+	}
+
+	target.Rules, err = ParseAlertRules(spec.Rules)
+	return target, err
+}
+
+/* WatchTarget Receivers */
+func (target WatchTarget) Name() string {
+	if target.Label != "" {
+		return target.Label
+	} else if target.Course != "" {
+		return target.Course
+	}
+
+	CRNs := make([]string, 0, len(target.Filter.CRNs))
+	for _, CRN := range target.Filter.CRNs {
+		CRNs = append(CRNs, strconv.Itoa(CRN))
+	}
+	return "CRN " + strings.Join(CRNs, ", ")
+}
+
+func (target WatchTarget) AlertRules() (rules []AlertRule) {
+	if len(target.Rules) == 0 {
+		return DefaultRules
+	}
+	return target.Rules
+}
+
+// Query is the filter of target, limited to its course.
+func (target WatchTarget) Query() (query FilterInfo) {
+	query = target.Filter
+	if target.Course == "" {
+		return query
+	}
+
+	query.Courses = []string{target.Course}
+
+	// only the course subject needs fetching
+	subject := strings.ToLower(strings.Fields(target.Course)[0])
+	query.Departments = []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(subject) + "$")}
+
+	return query
+}
+
+// Resolve finds the sections of classes target currently matches.
+func (target WatchTarget) Resolve(classes ClassList) (sections ClassList) {
+	sections = classes.Filter(target.Query())
+
+	// keep sections in a stable order for notifications
+	sort.Slice(sections.List, func(i, j int) bool {
+		return sections.List[i].Section < sections.List[j].Section
+	})
+
+	return sections
+}
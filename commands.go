@@ -1,96 +1,125 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/lyokum/cscli/classsearch"
 	"github.com/mattn/go-isatty"
 	"github.com/urfave/cli"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 /* init functions */
 func setDirectory(ctx *cli.Context, dir string) (err error) {
 	// check that cache is being used
 	if ctx.Bool("no-cache") {
-		return ErrNoCache
+		return classsearch.ErrNoCache
+	}
+
+	// cache import can fill a directory that doesn't exist yet
+	if args := ctx.Args(); len(args) > 1 && args[0] == "cache" && args[1] == "import" {
+		err = os.MkdirAll(dir, 0700)
+		if err != nil {
+			return
+		}
 	}
 
 	// set cache directories
 	return Storage.SetDirectory(dir)
 }
 
-/* Helper Funcs */
-func getAllClasses(ctx *cli.Context, CRNs []int) (classes ClassList, err error) {
-	log.Println("Fetching all classes")
+// printWarnings shows problems the client worked around, even without
+// --debug.
+func printWarnings() {
+	for _, warning := range Client.Warnings() {
+		fmt.Fprintln(os.Stderr, "WARNING: "+warning.Error())
+	}
+}
 
-	// use cache if specified
-	if ctx.Parent().Bool("no-cache") {
-		// get generic page
-		doc, err := ParseHTML("")
-		if err != nil {
-			return classes, err
+func requireCache(need classsearch.CacheNeed) cli.BeforeFunc {
+	return func(ctx *cli.Context) (err error) {
+		// nothing to restore without a cache
+		if ctx.GlobalBool("no-cache") {
+			return nil
 		}
 
-		// get options from page
-		opts, err := GetOptions(doc)
+		err = openStore(ctx)
 		if err != nil {
-			return classes, err
+			return
 		}
 
-		// setup form input for general search
-		var input FormInput
-		input.Init(opts)
-		doc, err = ParseHTML(input.String())
+		return Storage.Require(RequestContext, need)
+	}
+}
+
+// openCache opens the store for commands that manage the cache itself.
+func openCache(ctx *cli.Context) (err error) {
+	if ctx.GlobalBool("no-cache") {
+		return classsearch.ErrNoCache
+	}
+
+	return openStore(ctx)
+}
+
+func openStore(ctx *cli.Context) (err error) {
+	// create default per-user directory
+	if ctx.GlobalString("directory") == "" {
+		err = Storage.MakeDirectory()
 		if err != nil {
-			return classes, err
+			return
 		}
+	}
+
+	return Storage.Open(ctx.GlobalString("store"))
+}
+
+/* Helper Funcs */
+func getAllClasses(ctx *cli.Context, info classsearch.FilterInfo, CRNs []int) (classes classsearch.ClassList, err error) {
+	log.Println("Fetching all classes")
+
+	// get classes the filter could match
+	classes, err = Client.Classes(RequestContext, info)
+	if err != nil {
+		return
+	}
 
-		// get all classes
-		classes, err = GetClasses(doc, nil)
+	// update cached data based on CRNs
+	if len(CRNs) > 0 && Client.Cache != nil {
+		err = Client.Update(RequestContext, CRNs)
 		if err != nil {
-			return classes, err
-		}
-	} else {
-		if len(CRNs) > 0 {
-			// update data based on CRNs
-			err = Storage.FetchUpdates(CRNs)
-			if err != nil {
-				return classes, err
-			}
+			return
 		}
-
-		// get all classes
-		classes = Storage.Classes
+		classes = Client.Cache.Classes
 	}
 
 	log.Println("All classes fetched")
 	return classes, nil
 }
 
-func slice2Regex(slice []string) (regs []*regexp.Regexp, err error) {
-	regs = make([]*regexp.Regexp, 0, 10)
+func getDetails(ctx *cli.Context, classes classsearch.ClassList) (detailed classsearch.ClassList, err error) {
+	return Client.Details(RequestContext, classes)
+}
 
-	for _, str := range slice {
-		expr, err := regexp.Compile(strings.ToLower(str))
-		if err != nil {
-			return regs, err
-		}
-		regs = append(regs, expr)
+func getTranscript(ctx *cli.Context) (transcript classsearch.Transcript, err error) {
+	filename := ctx.Parent().String("transcript")
+	if filename == "" {
+		return transcript, classsearch.ErrNoTranscript
 	}
 
-	return regs, nil
+	return classsearch.LoadTranscript(filename)
 }
 
-/* check command */
-func checkCRNs(ctx *cli.Context) (err error) {
-	log.Println("Checking CRNs")
-
+func getCRNs(ctx *cli.Context) (CRNs []int, err error) {
 	// get CRNs in string form
-	CRNs := make([]int, 0, 10)
+	CRNs = make([]int, 0, 10)
 	strCRNs := make([]string, 0, 10)
 	if ctx.NArg() > 0 {
 		CRNs = make([]int, 0, 10)
@@ -103,7 +132,7 @@ func checkCRNs(ctx *cli.Context) (err error) {
 		// read in stdin
 		CRNinput, err := ioutil.ReadAll(os.Stdin)
 		if err != nil {
-			return err
+			return CRNs, err
 		}
 
 		// create tokens
@@ -116,27 +145,206 @@ func checkCRNs(ctx *cli.Context) (err error) {
 		for _, strCRN := range strCRNs {
 			CRN, err := strconv.Atoi(strCRN)
 			if err != nil {
-				return err
+				return CRNs, err
 			}
 
 			CRNs = append(CRNs, CRN)
 		}
 	} else {
-		return ErrNoCRNs
+		return CRNs, ErrNoCRNs
 	}
 
-	// get full class repo
-	fullList, err := getAllClasses(ctx, CRNs)
+	return CRNs, nil
+}
+
+func selectClasses(classes classsearch.ClassList, CRNs []int) (selected []classsearch.Class, err error) {
+	selected = make([]classsearch.Class, 0, len(CRNs))
+
+	// check that all CRNs exist
+	for _, CRN := range CRNs {
+		class, ok := classes.Map[CRN]
+		if !ok {
+			return selected, &classsearch.CRNError{CRN: CRN, Err: classsearch.ErrNoClass}
+		}
+
+		selected = append(selected, class)
+	}
+
+	return selected, nil
+}
+
+func getAttributes(patterns []string) (groups [][]string, err error) {
+	exprs, err := slice2Regex(patterns)
 	if err != nil {
 		return
 	}
 
-	// filter classes
-	filterinfo := FilterInfo{CRNs: CRNs}
-	classes := fullList.Filter(filterinfo)
+	opts, err := Client.Options(RequestContext)
+	if err != nil {
+		return
+	}
+
+	// match codes or names of attributes
+	for _, expr := range exprs {
+		group := make([]string, 0, 5)
+		for _, code := range classsearch.AllAttributes(opts) {
+			if expr.MatchString(strings.ToLower(code)) || expr.MatchString(strings.ToLower(opts.Attributes[code])) {
+				group = append(group, code)
+			}
+		}
+
+		if len(group) == 0 {
+			return groups, ErrUnknownAttribute
+		}
+		groups = append(groups, group)
+	}
+
+	return groups, nil
+}
+
+func slice2Regex(slice []string) (regs []*regexp.Regexp, err error) {
+	regs = make([]*regexp.Regexp, 0, 10)
+
+	for _, str := range slice {
+		expr, err := regexp.Compile(strings.ToLower(str))
+		if err != nil {
+			return regs, err
+		}
+		regs = append(regs, expr)
+	}
+
+	return regs, nil
+}
+
+/* check command */
+func checkCRNs(ctx *cli.Context) (err error) {
+	log.Println("Checking CRNs")
+
+	targets, err := getTargets(ctx)
+	if err != nil {
+		return
+	}
+
+	notifinfo, err := getNotifInfo(ctx)
+	if err != nil {
+		return
+	}
+
+	// make sure rules only pick notifiers that are set up
+	for _, target := range targets {
+		for _, rule := range target.AlertRules() {
+			err = rule.CheckNotifiers(notifinfo)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	policy, err := getNotifyPolicy(ctx)
+	if err != nil {
+		return
+	}
+
+	// compare with sections seen by the last check
+	snapshot := classsearch.NewSnapshot()
+	if Client.Cache != nil {
+		snapshot, err = Client.Cache.ReadSnapshot()
+		if err != nil {
+			return
+		}
+	}
+
+	// only kept between polls without a cache
+	var ledger classsearch.Ledger
+
+	// keep polling until interrupted if asked
+	for {
+		err = checkTargets(targets, notifinfo, policy, &snapshot, &ledger)
+
+		// show warnings of each poll as it happens
+		printWarnings()
+		if err != nil || ctx.Duration("interval") <= 0 {
+			return err
+		}
+
+		select {
+		case <-RequestContext.Done():
+			log.Println("Stopped checking")
+			return nil
+		case <-time.After(ctx.Duration("interval")):
+		}
+	}
+}
+
+func getTargets(ctx *cli.Context) (targets []classsearch.WatchTarget, err error) {
+	rules, err := classsearch.ParseAlertRules(ctx.StringSlice("rule"))
+	if err != nil {
+		return
+	}
+
+	// targets with their own rules
+	if filename := ctx.String("watch"); filename != "" {
+		targets, err = classsearch.LoadWatchFile(filename)
+		if err != nil {
+			return
+		}
+
+		for i := range targets {
+			if len(targets[i].Rules) == 0 {
+				targets[i].Rules = rules
+			}
+		}
+	}
 
-	// send notifications
-	notifinfo := NotifInfo{SendUpdate: ctx.Bool("update"), SendText: ctx.Bool("text")}
+	// get CRNs from args or stdin, optional when watching courses
+	courses := ctx.StringSlice("course")
+	CRNs, err := getCRNs(ctx)
+	if err == ErrNoCRNs && (len(courses) > 0 || len(targets) > 0) {
+		err = nil
+	} else if err != nil {
+		return
+	}
+
+	for _, CRN := range CRNs {
+		target := classsearch.CRNTarget(CRN)
+		target.Rules = rules
+		targets = append(targets, target)
+	}
+
+	// restrict sections of courses
+	var filter classsearch.FilterInfo
+	if len(ctx.StringSlice("professor")) > 0 {
+		filter.Professors, err = slice2Regex(ctx.StringSlice("professor"))
+		if err != nil {
+			return
+		}
+	}
+
+	if after := ctx.String("after"); after != "" {
+		filter.After, err = classsearch.ParseClock(after)
+		if err != nil {
+			return
+		}
+	}
+
+	if before := ctx.String("before"); before != "" {
+		filter.Before, err = classsearch.ParseClock(before)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, course := range courses {
+		target := classsearch.CourseTarget(course, filter)
+		target.Rules = rules
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+func getNotifInfo(ctx *cli.Context) (notifinfo classsearch.NotifInfo, err error) {
+	notifinfo = classsearch.NotifInfo{SendUpdate: ctx.Bool("update"), SendText: ctx.Bool("text")}
 
 	// get server info
 	if notifinfo.SendUpdate {
@@ -145,7 +353,7 @@ func checkCRNs(ctx *cli.Context) (err error) {
 
 		// check no server specified
 		if len(notifinfo.Server) == 0 {
-			return ErrServerNotFound
+			return notifinfo, ErrServerNotFound
 		}
 
 		// test connection
@@ -163,7 +371,7 @@ func checkCRNs(ctx *cli.Context) (err error) {
 
 		// check number of digits
 		if len(notifinfo.Phone) != 10 {
-			return ErrPhoneInvalid
+			return notifinfo, ErrPhoneInvalid
 		}
 
 		// get provider
@@ -171,20 +379,155 @@ func checkCRNs(ctx *cli.Context) (err error) {
 		notifinfo.Provider, ok = Providers[strings.ToLower(provider)]
 
 		if !ok {
-			return ErrProviderNotFound
+			return notifinfo, ErrProviderNotFound
+		}
+	}
+
+	return notifinfo, nil
+}
+
+func getNotifyPolicy(ctx *cli.Context) (policy classsearch.NotifyPolicy, err error) {
+	policy.Cooldown = ctx.Duration("cooldown")
+
+	if quiet := ctx.String("quiet-hours"); quiet != "" {
+		policy.Quiet, err = classsearch.ParseQuietHours(quiet)
+	}
+
+	return policy, err
+}
+
+func checkTargets(targets []classsearch.WatchTarget, notifinfo classsearch.NotifInfo, policy classsearch.NotifyPolicy, snapshot *classsearch.Snapshot, ledger *classsearch.Ledger) (err error) {
+	// refetch so new sections are seen
+	polled, err := Client.Poll(RequestContext, targets)
+	if err != nil {
+		return
+	}
+
+	// gather sections and alerts of all targets without repeats
+	var classes classsearch.ClassList
+	classes.Init()
+	alerts := make([]classsearch.Alert, 0, 10)
+	sent := make(map[string]bool)
+	for _, target := range targets {
+		sections := target.Resolve(polled)
+		if len(sections.List) == 0 {
+			// CRNs must exist when first watched, but may be cancelled since
+			// and courses may not have sections yet
+			if _, seen := snapshot.Sections[target.Name()]; target.Course == "" && !seen {
+				return classsearch.ErrNoClass
+			}
+			log.Println("No sections of", target.Name())
+		}
+
+		for _, class := range sections.List {
+			if _, ok := classes.Map[class.CRN]; !ok {
+				classes.Add(class)
+			}
+		}
+
+		for _, alert := range snapshot.Evaluate(target, sections, polled) {
+			key := fmt.Sprintf("%d %s", alert.Class.CRN, alert.Rule)
+			if !sent[key] {
+				sent[key] = true
+				alerts = append(alerts, alert)
+			}
+		}
+		snapshot.Update(target, sections)
+	}
+
+	// without rules keep the output check has always had
+	ruled := false
+	for _, target := range targets {
+		ruled = ruled || len(target.Rules) > 0
+	}
+
+	if !ruled {
+		for i := range alerts {
+			class := alerts[i].Class
+			alerts[i].Message = fmt.Sprintf("%s (CRN %d) has %d open slot(s)!", class.Title, class.CRN, class.Open)
 		}
 	}
 
-	// send notification
-	classes.Notify(notifinfo)
+	// drop repeats and hold alerts during quiet hours
+	if Client.Cache != nil {
+		alerts, err = Client.Cache.ApplyLedger(alerts, policy)
+		if err != nil {
+			return
+		}
+	} else {
+		alerts = ledger.Apply(alerts, policy, time.Now())
+	}
+
+	// print whether sections are open and alerts picked for printing
+	for _, class := range classes.List {
+		available := "X"
+		if class.Open > 0 {
+			available = "O"
+		}
+
+		if ruled {
+			fmt.Printf("%s: %s %s %s\n", available, class.Section, class.Title, class.Instructor)
+		} else {
+			fmt.Printf("%s: %s %s\n", available, class.Title, class.Instructor)
+		}
+	}
+
+	for _, alert := range alerts {
+		if alert.Printed(notifinfo) {
+			fmt.Println(alert.Message)
+		}
+	}
+
+	// send summary of every section, then alerts picked by rules
+	classes.SendSummary(notifinfo)
+	classsearch.SendAlerts(notifinfo, alerts)
 	log.Println("CRNs checked and notified")
+
+	// remember sections for next check
+	if Client.Cache != nil {
+		return Client.Cache.WriteSnapshot(*snapshot)
+	}
+	return nil
+}
+
+/* notifications command */
+func notificationsLog(ctx *cli.Context) (err error) {
+	log.Println("Reading notification ledger")
+
+	ledger, err := Storage.ReadLedger()
+	if err != nil {
+		return
+	}
+
+	since := time.Time{}
+	if ctx.Duration("since") > 0 {
+		since = time.Now().Add(-ctx.Duration("since"))
+	}
+
+	// print oldest first, one line per entry
+	for _, entry := range ledger.Since(since) {
+		status := entry.Status
+		if !entry.Delivered.IsZero() {
+			status += " (digest " + entry.Delivered.Local().Format("2006-01-02 15:04") + ")"
+		}
+
+		CRN := ""
+		if entry.CRN != 0 {
+			CRN = fmt.Sprintf("%d", entry.CRN)
+		}
+
+		message := strings.Replace(entry.Message, "\n", "; ", -1)
+		fmt.Println(strings.Join([]string{entry.Time.Local().Format("2006-01-02 15:04:05"), status, CRN, entry.Rule, message, entry.Reason}, "\t"))
+	}
+
+	log.Println("Notification ledger read")
 	return nil
 }
 
 /* search command */
 func performSearch(ctx *cli.Context) (err error) {
 	log.Println("Starting search")
-	var info FilterInfo
+	var info classsearch.FilterInfo
 
 	// check open
 	if ctx.Bool("open") {
@@ -215,6 +558,14 @@ func performSearch(ctx *cli.Context) (err error) {
 		}
 	}
 
+	// check attributes
+	if len(ctx.StringSlice("attribute")) > 0 {
+		info.Attributes, err = getAttributes(ctx.StringSlice("attribute"))
+		if err != nil {
+			return err
+		}
+	}
+
 	// check names
 	if ctx.NArg() > 0 {
 		info.Names, err = slice2Regex(ctx.Args())
@@ -224,7 +575,7 @@ func performSearch(ctx *cli.Context) (err error) {
 	}
 
 	// get full class repo
-	classes, err := getAllClasses(ctx, info.CRNs)
+	classes, err := getAllClasses(ctx, info, info.CRNs)
 	if err != nil {
 		return
 	}
@@ -232,6 +583,22 @@ func performSearch(ctx *cli.Context) (err error) {
 	// filter classes
 	results := classes.Filter(info)
 
+	// hide classes that cannot be registered for
+	if ctx.Bool("eligible") && len(results.Map) > 0 {
+		transcript, err := getTranscript(ctx)
+		if err != nil {
+			return err
+		}
+
+		results, err = getDetails(ctx, results)
+		if err != nil {
+			return err
+		}
+
+		info.Transcript = &transcript
+		results = results.Filter(info)
+	}
+
 	// update results if necessary
 	if !ctx.Parent().Bool("no-cache") && len(results.Map) > 0 && ctx.Bool("update") {
 		updateCRNs := make([]int, 0, 10)
@@ -242,7 +609,7 @@ func performSearch(ctx *cli.Context) (err error) {
 		}
 
 		// update cache
-		err = Storage.FetchUpdates(updateCRNs)
+		err = Storage.FetchUpdates(RequestContext, updateCRNs)
 		if err != nil {
 			return err
 		}
@@ -269,20 +636,615 @@ func performSearch(ctx *cli.Context) (err error) {
 	return nil
 }
 
-/* refresh command */
-func refreshCache(ctx *cli.Context) (err error) {
-	log.Println("Deleting files and forcing refresh")
+/* eligible command */
+func checkEligibility(ctx *cli.Context) (err error) {
+	log.Println("Checking eligibility")
 
-	// remove cache files if present
-	os.Remove(Storage.OptCache.Info.Filename)
-	os.Remove(Storage.Info.Filename)
+	// get CRNs from args or stdin
+	CRNs, err := getCRNs(ctx)
+	if err != nil {
+		return
+	}
 
-	// force refresh
-	err = Storage.Restore()
+	transcript, err := getTranscript(ctx)
 	if err != nil {
 		return
 	}
 
-	log.Println("Refresh complete")
+	// get full class repo
+	fullList, err := getAllClasses(ctx, classsearch.FilterInfo{CRNs: CRNs}, nil)
+	if err != nil {
+		return
+	}
+
+	// check that all CRNs exist
+	for _, CRN := range CRNs {
+		if _, ok := fullList.Map[CRN]; !ok {
+			return &classsearch.CRNError{CRN: CRN, Err: classsearch.ErrNoClass}
+		}
+	}
+
+	// get section details for classes
+	classes, err := getDetails(ctx, fullList.Filter(classsearch.FilterInfo{CRNs: CRNs}))
+	if err != nil {
+		return
+	}
+
+	log.Println("Printing eligibility results")
+
+	// print results in given order
+	for _, CRN := range CRNs {
+		class := classes.Map[CRN]
+		result := class.CheckEligibility(transcript)
+		fmt.Println(strings.Join([]string{fmt.Sprintf("%d", class.CRN), result.Status.String(), class.Title, result.Reason}, "\t"))
+	}
+
+	log.Println("Eligibility checked")
 	return nil
 }
+
+/* attributes command */
+func listAttributes(ctx *cli.Context) (err error) {
+	log.Println("Listing attributes")
+
+	// get CRNs from args or stdin
+	CRNs, err := getCRNs(ctx)
+	if err != nil {
+		return
+	}
+
+	opts, err := Client.Options(RequestContext)
+	if err != nil {
+		return
+	}
+
+	// check every attribute unless some are given
+	codes := classsearch.AllAttributes(opts)
+	if len(ctx.StringSlice("attribute")) > 0 {
+		groups, err := getAttributes(ctx.StringSlice("attribute"))
+		if err != nil {
+			return err
+		}
+		codes = classsearch.FilterInfo{Attributes: groups}.AttributeCodes()
+	}
+
+	// get full class repo
+	fullList, err := getAllClasses(ctx, classsearch.FilterInfo{CRNs: CRNs}, nil)
+	if err != nil {
+		return
+	}
+
+	// check that all CRNs exist
+	for _, CRN := range CRNs {
+		if _, ok := fullList.Map[CRN]; !ok {
+			return &classsearch.CRNError{CRN: CRN, Err: classsearch.ErrNoClass}
+		}
+	}
+
+	// tag classes with attributes they satisfy
+	classes, err := Client.Attributes(RequestContext, fullList.Filter(classsearch.FilterInfo{CRNs: CRNs}), codes)
+	if err != nil {
+		return
+	}
+
+	log.Println("Printing attributes")
+
+	// print results in given order, only with checked attributes
+	for _, CRN := range CRNs {
+		class := classes.Map[CRN]
+		names := make([]string, 0, len(class.Attributes))
+		for _, code := range codes {
+			if class.HasAttribute([]string{code}) {
+				names = append(names, code+" "+opts.Attributes[code])
+			}
+		}
+		fmt.Println(strings.Join([]string{fmt.Sprintf("%d", class.CRN), class.Title, strings.Join(names, "; ")}, "\t"))
+	}
+
+	log.Println("Attributes listed")
+	return nil
+}
+
+/* professors command */
+func listProfessors(ctx *cli.Context) (err error) {
+	log.Println("Listing professors")
+	var info classsearch.FilterInfo
+
+	// check open
+	if ctx.Bool("open") {
+		info.Open = true
+	}
+
+	// check departments
+	if len(ctx.StringSlice("department")) > 0 {
+		info.Departments, err = slice2Regex(ctx.StringSlice("department"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// check professors
+	var professors []*regexp.Regexp
+	if ctx.NArg() > 0 {
+		professors, err = slice2Regex(ctx.Args())
+		if err != nil {
+			return err
+		}
+	}
+
+	// get full class repo
+	classes, err := getAllClasses(ctx, info, nil)
+	if err != nil {
+		return
+	}
+
+	log.Println("Printing professors")
+
+	// print sections grouped by instructor
+	for _, instructor := range classes.Filter(info).ByInstructor(professors) {
+		fmt.Printf("%s (%d section(s), %d/%d open)\n", instructor.Name, len(instructor.Classes), instructor.Open, instructor.Max)
+
+		for _, class := range instructor.Classes {
+			fmt.Println("\t" + strings.Join([]string{fmt.Sprintf("%d", class.CRN), class.Section, class.Title, fmt.Sprintf("%d/%d", class.Open, class.Max)}, "\t"))
+		}
+	}
+
+	log.Println("Professors listed")
+	return nil
+}
+
+/* rooms command */
+func listRooms(ctx *cli.Context) (err error) {
+	log.Println("Listing rooms")
+
+	// check rooms
+	var rooms []*regexp.Regexp
+	if ctx.NArg() > 0 {
+		rooms, err = slice2Regex(ctx.Args())
+		if err != nil {
+			return err
+		}
+	}
+
+	// check time range before fetching anything
+	var day time.Weekday
+	var start, end int
+	if ctx.Bool("free") {
+		if ctx.String("day") == "" || ctx.String("from") == "" || ctx.String("to") == "" {
+			return ErrFreeRange
+		}
+
+		day, err = classsearch.ParseWeekday(ctx.String("day"))
+		if err != nil {
+			return err
+		}
+
+		start, err = classsearch.ParseClock(ctx.String("from"))
+		if err != nil {
+			return err
+		}
+
+		end, err = classsearch.ParseClock(ctx.String("to"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// get full class repo
+	classes, err := getAllClasses(ctx, classsearch.FilterInfo{}, nil)
+	if err != nil {
+		return
+	}
+
+	schedules := classes.ByRoom(rooms)
+
+	// print rooms free during time range
+	if ctx.Bool("free") {
+		log.Println("Printing free rooms")
+		for _, schedule := range schedules {
+			if schedule.IsFree(day, start, end) {
+				fmt.Println(schedule.Room)
+			}
+		}
+
+		log.Println("Rooms listed")
+		return nil
+	}
+
+	log.Println("Printing room timetables")
+
+	// print weekly timetable of each room
+	for _, schedule := range schedules {
+		fmt.Println(schedule.Room)
+
+		for _, slot := range schedule.Slots {
+			fmt.Println("\t" + strings.Join([]string{slot.Day.String()[:3], classsearch.FormatClock(slot.Start) + "-" + classsearch.FormatClock(slot.End), fmt.Sprintf("%d", slot.Class.CRN), slot.Class.Section, slot.Class.Title}, "\t"))
+		}
+	}
+
+	log.Println("Rooms listed")
+	return nil
+}
+
+/* export-ics command */
+func exportICS(ctx *cli.Context) (err error) {
+	log.Println("Exporting calendar")
+	var opts classsearch.ICSOptions
+
+	// get CRNs from args or stdin
+	CRNs, err := getCRNs(ctx)
+	if err != nil {
+		return
+	}
+
+	// get term date overrides
+	if start := ctx.String("start"); start != "" {
+		opts.Start, err = classsearch.ParseDate(start)
+		if err != nil {
+			return err
+		}
+	}
+
+	if end := ctx.String("end"); end != "" {
+		opts.End, err = classsearch.ParseDate(end)
+		if err != nil {
+			return err
+		}
+	}
+
+	// scraped dates have no year, take it from the term
+	searchOpts, err := Client.Options(RequestContext)
+	if err != nil {
+		return
+	}
+
+	opts.Year, err = classsearch.TermYear(searchOpts.Term(Client.Term))
+	if err != nil {
+		return
+	}
+
+	// get holidays to exclude
+	if holidays := ctx.String("holidays"); holidays != "" {
+		opts.Holidays, err = classsearch.LoadHolidays(holidays)
+		if err != nil {
+			return err
+		}
+	}
+
+	// get full class repo
+	fullList, err := getAllClasses(ctx, classsearch.FilterInfo{CRNs: CRNs}, nil)
+	if err != nil {
+		return
+	}
+
+	// get classes in given order
+	classes, err := selectClasses(fullList, CRNs)
+	if err != nil {
+		return
+	}
+
+	// write to file or stdout
+	out := os.Stdout
+	if filename := ctx.String("output"); filename != "" {
+		out, err = os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	err = classsearch.WriteICS(out, classes, opts)
+	if err != nil {
+		return
+	}
+
+	log.Println("Calendar exported")
+	return nil
+}
+
+/* timetable command */
+func showTimetable(ctx *cli.Context) (err error) {
+	log.Println("Building timetable")
+
+	// get CRNs from args or stdin
+	CRNs, err := getCRNs(ctx)
+	if err != nil {
+		return
+	}
+
+	// get full class repo
+	fullList, err := getAllClasses(ctx, classsearch.FilterInfo{CRNs: CRNs}, nil)
+	if err != nil {
+		return
+	}
+
+	// get classes in given order
+	classes, err := selectClasses(fullList, CRNs)
+	if err != nil {
+		return
+	}
+
+	// only color when printing to terminal
+	color := isatty.IsTerminal(os.Stdout.Fd()) && !ctx.Bool("no-color")
+
+	err = classsearch.RenderTimetable(os.Stdout, classes, color)
+	if err != nil {
+		return
+	}
+
+	log.Println("Timetable printed")
+	return nil
+}
+
+/* options command */
+func listOptions(ctx *cli.Context) (err error) {
+	log.Println("Listing search options")
+
+	// get options from cache or site
+	opts, err := Client.Options(RequestContext)
+	if err != nil {
+		return
+	}
+
+	categories := map[string]map[string]string{
+		"terms":      opts.Terms,
+		"divisions":  opts.Divisions,
+		"campuses":   opts.Campuses,
+		"subjects":   opts.Subjects,
+		"attributes": opts.Attributes,
+		"credits":    opts.Credits,
+	}
+
+	// default to subjects
+	name := strings.ToLower(ctx.Args().First())
+	if name == "" {
+		name = "subjects"
+	}
+
+	category, ok := categories[name]
+	if !ok {
+		return ErrUnknownCategory
+	}
+
+	// print codes in order
+	codes := make([]string, 0, len(category))
+	for code := range category {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		fmt.Println(code + "\t" + category[code])
+	}
+
+	log.Println("Options listed")
+	return nil
+}
+
+/* migrate command */
+func migrateStore(ctx *cli.Context) (err error) {
+	log.Println("Migrating store")
+
+	// check that target store differs from current
+	target := ctx.Args().First()
+	if target == "" {
+		return classsearch.ErrUnknownStore
+	}
+
+	if target == ctx.Parent().String("store") {
+		return classsearch.ErrSameStore
+	}
+
+	// nothing to copy from a store that was never filled
+	if len(Storage.Subjects) == 0 {
+		return classsearch.ErrEmptySource
+	}
+
+	// open target store next to current one
+	backend, err := classsearch.OpenBackend(target, Storage.Info.Directory, ctx.GlobalDuration("lock-wait"))
+	if err != nil {
+		return
+	}
+	defer backend.Close()
+
+	// only overwrite data already in target when asked to
+	empty, err := classsearch.StoreEmpty(backend)
+	if err != nil {
+		return
+	}
+
+	if !empty && !ctx.Bool("force") {
+		return classsearch.ErrTargetInUse
+	}
+
+	// copy all cached data
+	err = Storage.CopyTo(backend)
+	if err != nil {
+		return
+	}
+
+	log.Println("Migration complete")
+	return nil
+}
+
+/* refresh command */
+func refreshCache(ctx *cli.Context) (err error) {
+	log.Println("Forcing refresh")
+
+	// stored files are only replaced once everything is fetched
+	err = Storage.Refresh(RequestContext)
+	if err != nil {
+		return
+	}
+
+	log.Println("Refresh complete")
+	return nil
+}
+
+/* cache commands */
+func cacheStatus(ctx *cli.Context) (err error) {
+	log.Println("Reading cache status")
+
+	// read stored data without refreshing
+	err = Storage.ReadStored()
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("store:\t%s\n", Storage.StoreType())
+	fmt.Printf("directory:\t%s\n", Storage.Info.Directory)
+
+	// print files with sizes
+	for _, file := range Storage.Files() {
+		size := "missing"
+		if info, err := os.Stat(file); err == nil {
+			size = fmt.Sprintf("%d bytes", info.Size())
+		}
+		fmt.Printf("file:\t%s (%s)\n", file, size)
+	}
+
+	// print ages against refresh rates
+	for _, info := range []classsearch.CacheInfo{Storage.OptCache.Info, Storage.Info} {
+		state := "fresh"
+		if info.IsStale() {
+			state = "stale"
+		}
+
+		age := time.Now().Sub(info.Timestamp).Round(time.Second)
+		fmt.Printf("%s:\tupdated %s (%s ago), refresh every %s (%s)\n", info.Filename, info.Timestamp.Format(time.RFC1123), age, info.Rate, state)
+	}
+
+	// load every subject to count classes
+	subjects := make([]string, 0, len(Storage.Subjects))
+	stale := 0
+	for subject, info := range Storage.Subjects {
+		subjects = append(subjects, subject)
+		if info.IsStale() {
+			stale++
+		}
+	}
+
+	err = Storage.LoadSubjects(subjects)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("subjects:\t%d cached (%d stale) of %d offered\n", len(subjects), stale, len(Storage.OptCache.Options.Subjects))
+	fmt.Printf("classes:\t%d\n", len(Storage.Classes.Map))
+
+	log.Println("Cache status printed")
+	return nil
+}
+
+func cachePath(ctx *cli.Context) (err error) {
+	fmt.Println(Storage.Info.Directory)
+	for _, file := range Storage.Files() {
+		fmt.Println(file)
+	}
+
+	return nil
+}
+
+func cacheClear(ctx *cli.Context) (err error) {
+	err = Storage.Clear()
+	if err != nil {
+		return
+	}
+
+	log.Println("Cache cleared")
+	return nil
+}
+
+func cacheExport(ctx *cli.Context) (err error) {
+	log.Println("Exporting cache")
+
+	// read stored data without refreshing
+	err = Storage.ReadStored()
+	if err != nil {
+		return
+	}
+
+	// copy all data into memory
+	memory := &classsearch.MemoryBackend{}
+	err = Storage.CopyTo(memory)
+	if err != nil {
+		return
+	}
+
+	// write archive to file or stdout
+	out := os.Stdout
+	if filename := ctx.Args().First(); filename != "" && filename != "-" {
+		out, err = os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+	}
+
+	err = classsearch.WriteArchive(out, memory)
+	if err != nil {
+		return
+	}
+
+	log.Println("Cache exported")
+	return nil
+}
+
+func cacheImport(ctx *cli.Context) (err error) {
+	log.Println("Importing cache")
+
+	// read archive from file or stdin
+	in := os.Stdin
+	if filename := ctx.Args().First(); filename != "" && filename != "-" {
+		in, err = os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+
+	memory, err := classsearch.ReadArchive(in)
+	if err != nil {
+		return
+	}
+
+	err = Storage.Import(memory)
+	if err != nil {
+		return
+	}
+
+	log.Println("Cache imported")
+	return nil
+}
+
+/* fake-server command */
+func runFakeServer(ctx *cli.Context) (err error) {
+	log.Println("Starting fake server")
+
+	server, err := classsearch.NewFakeServer(ctx.String("dataset"))
+	if err != nil {
+		return
+	}
+
+	server.SeatChange = ctx.Duration("seat-change")
+	server.ErrorRate = ctx.Float64("error-rate")
+	server.Latency = ctx.Duration("latency")
+
+	address := ctx.String("listen")
+	fmt.Println("Serving fake class search on http://" + address)
+	fmt.Println("Use with: cscli --site http://" + address + " ...")
+
+	// stop serving on Ctrl-C or --timeout
+	httpServer := &http.Server{Addr: address, Handler: server}
+	go func() {
+		<-RequestContext.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+
+	err = httpServer.ListenAndServe()
+	if err == http.ErrServerClosed {
+		log.Println("Fake server stopped")
+		return nil
+	}
+	return err
+}
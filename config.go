@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/urfave/cli"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	ConfigFilename = "config.json"
+	profilesKey    = "profiles"
+)
+
+var (
+	ErrUnknownProfile = errors.New("Profile not found in config")
+)
+
+/* Config Functions */
+func DefaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "cscli", ConfigFilename)
+}
+
+// ApplyConfig fills global flags not given on the command line from a json
+// file whose keys are flag names, e.g. {"class-ttl": "12h"}. Settings under
+// "profiles" are only used when their profile is chosen, and take priority
+// over the rest of the file.
+func ApplyConfig(ctx *cli.Context, filename string) (err error) {
+	blob, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) && !ctx.IsSet("config") && !ctx.IsSet("profile") {
+		// default config is optional
+		return nil
+	} else if err != nil {
+		return
+	}
+
+	log.Println("Applying config", filename)
+
+	var config map[string]interface{}
+	err = json.Unmarshal(blob, &config)
+	if err != nil {
+		return
+	}
+
+	// split out profiles
+	profiles, _ := config[profilesKey].(map[string]interface{})
+	delete(config, profilesKey)
+
+	name := ctx.String("profile")
+	if !ctx.IsSet("profile") {
+		name, _ = config["profile"].(string)
+	}
+
+	if name != "" {
+		profile, ok := profiles[name].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %q", ErrUnknownProfile, name)
+		}
+
+		log.Println("Applying profile", name)
+		err = setFlags(ctx, profile)
+		if err != nil {
+			return
+		}
+	}
+
+	return setFlags(ctx, config)
+}
+
+func setFlags(ctx *cli.Context, config map[string]interface{}) (err error) {
+	for key, value := range config {
+		// command line and profiles take priority
+		if ctx.IsSet(key) {
+			continue
+		}
+
+		// lists fill slice flags one element at a time
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+
+		for _, value := range values {
+			err = ctx.Set(key, fmt.Sprint(value))
+			if err != nil {
+				return fmt.Errorf("config key %q: %v", key, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -1,23 +1,37 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/lyokum/cscli/classsearch"
 	"github.com/urfave/cli"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var (
-	ErrInvalidDirectory = errors.New("Invalid directory specified")
 	ErrNoCRNs           = errors.New("No CRNs given")
 	ErrServerNotFound   = errors.New("Server not found")
 	ErrPhoneInvalid     = errors.New("Invalid phone number")
 	ErrProviderNotFound = errors.New("Provider not known")
+	ErrInterrupted      = errors.New("Interrupted, cache left unchanged")
+	ErrRecordReplay     = errors.New("Cannot use --record and --replay together")
+	ErrUnknownCategory  = errors.New("Unknown option category (use terms, divisions, campuses, subjects, attributes or credits)")
+	ErrUnknownAttribute = errors.New("No attribute matches (see options attributes)")
+	ErrFreeRange        = errors.New("--free needs --day, --from and --to")
+	ErrTimedOut         = errors.New("Timed out waiting for site, cache left unchanged (raise --timeout)")
 
 	Providers = map[string]string{"att": "txt.att.net", "tmobile": "tmomail.net", "sprint": "messaging.sprintpcs.com", "verizon": "vtext.com"}
-	Storage   ClassCache
+	Storage   classsearch.ClassCache
+	Client    = classsearch.NewClient(&Storage)
+
+	// canceled on interrupt or --timeout to stop requests to the site
+	RequestContext                    = context.Background()
+	cancelRequests context.CancelFunc = func() {}
 )
 
 /* FIXME: remove these after testing */
@@ -44,13 +58,96 @@ func main() {
 			Name:  "no-cache, n",
 			Usage: "do not cache all classes into json files for quicker searches",
 		},
+		cli.BoolFlag{
+			Name:  "offline, o",
+			Usage: "never contact the class search site, only use cached data",
+		},
 		cli.BoolFlag{
 			Name:  "debug, i",
 			Usage: "enable debugging messages",
 		},
 		cli.StringFlag{
 			Name:  "directory, d",
-			Usage: "specify directory to put cache json files in",
+			Usage: "specify directory to put cache json files in (default: $XDG_CACHE_HOME/cscli)",
+		},
+		cli.StringFlag{
+			Name:  "config, c",
+			Usage: "specify json config `FILE` whose keys set any of these global flags",
+			Value: DefaultConfigFile(),
+		},
+		cli.DurationFlag{
+			Name:  "class-ttl",
+			Usage: "specify how long cached classes stay fresh",
+			Value: classsearch.DefaultClassRate,
+		},
+		cli.DurationFlag{
+			Name:  "options-ttl",
+			Usage: "specify how long cached search options stay fresh",
+			Value: classsearch.DefaultOptionsRate,
+		},
+		cli.DurationFlag{
+			Name:  "lock-wait, w",
+			Usage: "specify how long to wait for another cscli process to release the cache",
+			Value: classsearch.DefaultLockWait,
+		},
+		cli.StringFlag{
+			Name:  "store, s",
+			Usage: "specify cache `STORE` type: json files or bolt embedded database",
+			Value: classsearch.JSONStore,
+		},
+		cli.IntFlag{
+			Name:  "parallel",
+			Usage: "send at most `N` requests to the site at once",
+			Value: classsearch.DefaultParallel,
+		},
+		cli.Float64Flag{
+			Name:  "rate",
+			Usage: "send at most `N` requests per second to the site (0 for no limit)",
+			Value: classsearch.DefaultRate,
+		},
+		cli.DurationFlag{
+			Name:  "jitter",
+			Usage: "delay each request by a random amount up to `DURATION`",
+			Value: classsearch.DefaultJitter,
+		},
+		cli.StringFlag{
+			Name:  "profile, p",
+			Usage: "use settings of `PROFILE` from config file",
+		},
+		cli.StringFlag{
+			Name:  "source",
+			Usage: "fetch classes from `SOURCE` type: nd class search or banner 9 student registration",
+			Value: classsearch.NDSourceName,
+		},
+		cli.StringFlag{
+			Name:  "site",
+			Usage: "fetch from class search at base `URL`, e.g. one started with fake-server",
+			Value: classsearch.DefaultSite,
+		},
+		cli.StringFlag{
+			Name:  "term",
+			Usage: "search `TERM` code (newest term is used if not offered)",
+			Value: classsearch.DefaultTerm,
+		},
+		cli.StringFlag{
+			Name:  "record",
+			Usage: "save every page fetched from the site into fixture `DIR`",
+		},
+		cli.StringFlag{
+			Name:  "replay",
+			Usage: "serve pages saved with --record from fixture `DIR` instead of the site",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Usage: "give up on requests to the site after `DURATION` in total (0 for no limit)",
+		},
+		cli.BoolFlag{
+			Name:  "fail-fast",
+			Usage: "stop fetching and keep no results when any subject fails (default keeps subjects that were fetched)",
+		},
+		cli.StringFlag{
+			Name:  "transcript, t",
+			Usage: "specify transcript json `FILE` of completed courses, standing, major and college",
 		},
 	}
 
@@ -76,6 +173,10 @@ func main() {
 					Name:  "professor, p",
 					Usage: "restrict search to first or last name of `PROF`",
 				},
+				cli.StringSliceFlag{
+					Name:  "attribute, a",
+					Usage: "restrict search to classes with `ATTR` (code or name, repeat to require several)",
+				},
 				cli.BoolFlag{
 					Name:  "info, i",
 					Usage: "display more info than just the CRNs of found classes",
@@ -84,14 +185,57 @@ func main() {
 					Name:  "update, u",
 					Usage: "update class info of classes in search results from website",
 				},
+				cli.BoolFlag{
+					Name:  "eligible, e",
+					Usage: "hide classes whose prerequisites or restrictions are not met (requires --transcript)",
+				},
 			},
+			Before:                 requireCache(classsearch.NeedClasses),
 			Action:                 performSearch,
 			UseShortOptionHandling: true,
 		},
 		cli.Command{
-			Name:  "check",
-			Usage: "check to see if classes with specified CRNs are open",
+			Name:      "check",
+			Usage:     "check to see if classes with specified CRNs or any section of a course are open",
+			ArgsUsage: "[CRN...]",
 			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "course, C",
+					Usage: "watch every section of `COURSE` (e.g. \"CSE 30341\"), including ones added later",
+				},
+				cli.StringSliceFlag{
+					Name:  "professor, P",
+					Usage: "only watch course sections taught by first or last name of `PROF`",
+				},
+				cli.StringFlag{
+					Name:  "after, a",
+					Usage: "only watch course sections meeting no earlier than `TIME` (e.g. 12:00P)",
+				},
+				cli.StringFlag{
+					Name:  "before, b",
+					Usage: "only watch course sections meeting no later than `TIME` (e.g. 5:00P)",
+				},
+				cli.StringSliceFlag{
+					Name:  "rule, r",
+					Usage: "alert when `RULE` holds, as CONDITION[>=SEATS][:NOTIFIER,...] with conditions open, opened, capacity, instructor, meeting or cancelled and notifiers print, update or text (default open)",
+				},
+				cli.StringFlag{
+					Name:  "watch, w",
+					Usage: "also watch targets in json `FILE`, each with its own course or CRNs, filters and rules",
+				},
+				cli.DurationFlag{
+					Name:  "cooldown",
+					Usage: "don't repeat an alert for the same CRN and rule within `DURATION` (0 to always send)",
+					Value: classsearch.DefaultCooldown,
+				},
+				cli.StringFlag{
+					Name:  "quiet-hours, q",
+					Usage: "hold alerts during `START-END` (e.g. 22:00-7:00) and send them in one digest afterwards",
+				},
+				cli.DurationFlag{
+					Name:  "interval, n",
+					Usage: "keep checking every `DURATION` until interrupted instead of once",
+				},
 				cli.BoolFlag{
 					Name:  "update, u",
 					Usage: "send update to server (requires update-send)",
@@ -116,12 +260,218 @@ func main() {
 					Value: "",
 				},
 			},
+			Before:                 requireCache(classsearch.NeedClasses),
 			Action:                 checkCRNs,
 			UseShortOptionHandling: true,
 		},
+		cli.Command{
+			Name:      "eligible",
+			Usage:     "check prerequisites and restrictions of classes with specified CRNs against transcript",
+			ArgsUsage: "CRN...",
+			Before:    requireCache(classsearch.NeedClasses),
+			Action:    checkEligibility,
+		},
+		cli.Command{
+			Name:      "attributes",
+			Usage:     "list requirement attributes of classes with specified CRNs",
+			ArgsUsage: "CRN...",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "attribute, a",
+					Usage: "only check for `ATTR` (code or name) instead of every attribute",
+				},
+			},
+			Before: requireCache(classsearch.NeedClasses),
+			Action: listAttributes,
+		},
+		cli.Command{
+			Name:      "professors",
+			Usage:     "list sections and seat totals for each professor, optionally filtered by name with regex",
+			ArgsUsage: "[PROF...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "open, o",
+					Usage: "restrict to open courses",
+				},
+				cli.StringSliceFlag{
+					Name:  "department, d",
+					Usage: "restrict to `DEPT` (3 or 4 letter abbreviations)",
+				},
+			},
+			Before:                 requireCache(classsearch.NeedClasses),
+			Action:                 listProfessors,
+			UseShortOptionHandling: true,
+		},
+		cli.Command{
+			Name:      "rooms",
+			Usage:     "show weekly timetables of rooms, or rooms free at a given time, optionally filtered by building/room with regex",
+			ArgsUsage: "[ROOM...]",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "free, f",
+					Usage: "only list rooms with no classes during --day from --from to --to",
+				},
+				cli.StringFlag{
+					Name:  "day, w",
+					Usage: "specify `DAY` of week (e.g. T, Tue, Tuesday)",
+				},
+				cli.StringFlag{
+					Name:  "from, s",
+					Usage: "specify start `TIME` (e.g. 2:00P, 2pm, 14:00)",
+				},
+				cli.StringFlag{
+					Name:  "to, e",
+					Usage: "specify end `TIME` (e.g. 3:00P, 3pm, 15:00)",
+				},
+			},
+			Before:                 requireCache(classsearch.NeedClasses),
+			Action:                 listRooms,
+			UseShortOptionHandling: true,
+		},
+		cli.Command{
+			Name:      "export-ics",
+			Usage:     "export weekly meetings of classes with specified CRNs to an iCalendar (.ics) file",
+			ArgsUsage: "CRN...",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "output, o",
+					Usage: "write calendar to `FILE` instead of stdout",
+				},
+				cli.StringFlag{
+					Name:  "start, s",
+					Usage: "override term start `DATE` (e.g. 2019-08-20)",
+				},
+				cli.StringFlag{
+					Name:  "end, e",
+					Usage: "override term end `DATE` (e.g. 2019-12-06)",
+				},
+				cli.StringFlag{
+					Name:  "holidays, x",
+					Usage: "exclude dates listed one per line in `FILE`",
+				},
+			},
+			Before:                 requireCache(classsearch.NeedClasses),
+			Action:                 exportICS,
+			UseShortOptionHandling: true,
+		},
+		cli.Command{
+			Name:      "timetable",
+			Usage:     "show classes with specified CRNs on a weekly grid, highlighting overlaps",
+			ArgsUsage: "CRN...",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "no-color, m",
+					Usage: "disable colors even when printing to a terminal",
+				},
+			},
+			Before: requireCache(classsearch.NeedClasses),
+			Action: showTimetable,
+		},
+		cli.Command{
+			Name:      "migrate",
+			Usage:     "copy cached data from the current --store into another store type",
+			ArgsUsage: "STORE",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "overwrite cached data already in STORE",
+				},
+			},
+			Before: requireCache(classsearch.NeedClasses),
+			Action: migrateStore,
+		},
+		cli.Command{
+			Name:      "options",
+			Usage:     "list codes and names of search options in CATEGORY (terms, divisions, campuses, subjects, attributes or credits)",
+			ArgsUsage: "[CATEGORY]",
+			Before:    requireCache(classsearch.NeedOptions),
+			Action:    listOptions,
+		},
+		cli.Command{
+			Name:   "cache",
+			Usage:  "inspect and manage cache files",
+			Before: openCache,
+			Subcommands: []cli.Command{
+				cli.Command{
+					Name:   "status",
+					Usage:  "show cache paths, ages, refresh rates, counts and sizes",
+					Action: cacheStatus,
+				},
+				cli.Command{
+					Name:   "path",
+					Usage:  "print cache directory and files",
+					Action: cachePath,
+				},
+				cli.Command{
+					Name:   "clear",
+					Usage:  "delete cache files",
+					Action: cacheClear,
+				},
+				cli.Command{
+					Name:      "export",
+					Usage:     "write all cached data to a portable archive (stdout if no FILE)",
+					ArgsUsage: "[FILE]",
+					Action:    cacheExport,
+				},
+				cli.Command{
+					Name:      "import",
+					Usage:     "replace cached data with an archive from cache export (stdin if no FILE)",
+					ArgsUsage: "[FILE]",
+					Action:    cacheImport,
+				},
+			},
+		},
+		cli.Command{
+			Name:  "fake-server",
+			Usage: "serve a fake class search site for development and demos (use with --site)",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "listen, l",
+					Usage: "listen on `ADDRESS`",
+					Value: classsearch.DefaultFakeAddress,
+				},
+				cli.StringFlag{
+					Name:  "dataset, f",
+					Usage: "serve classes from json `FILE` (list of classes or class cache) instead of generated ones",
+				},
+				cli.DurationFlag{
+					Name:  "seat-change, s",
+					Usage: "randomly open and fill seats every `DURATION`",
+				},
+				cli.Float64Flag{
+					Name:  "error-rate, e",
+					Usage: "answer `FRACTION` of requests with a server error",
+				},
+				cli.DurationFlag{
+					Name:  "latency, d",
+					Usage: "delay answers by a random amount up to `DURATION`",
+				},
+			},
+			Action:                 runFakeServer,
+			UseShortOptionHandling: true,
+		},
+		cli.Command{
+			Name:   "notifications",
+			Usage:  "inspect alerts sent by check",
+			Before: openCache,
+			Subcommands: []cli.Command{
+				cli.Command{
+					Name:  "log",
+					Usage: "show alerts that were sent, suppressed, deferred or digested and why",
+					Flags: []cli.Flag{
+						cli.DurationFlag{
+							Name:  "since",
+							Usage: "only show entries from the last `DURATION`",
+						},
+					},
+					Action: notificationsLog,
+				},
+			},
+		},
 		cli.Command{
 			Name:   "refresh",
 			Usage:  "refresh cache files",
+			Before: openCache,
 			Action: refreshCache,
 		},
 	}
@@ -134,8 +484,49 @@ func main() {
 			log.SetOutput(ioutil.Discard)
 		}
 
+		// fill unset flags from config
+		err = ApplyConfig(ctx, ctx.String("config"))
+		if err != nil {
+			return
+		}
+
+		if ctx.Bool("debug") {
+			log.SetOutput(os.Stderr)
+		}
+
 		// init cache
 		Storage.Init()
+		Storage.SetLockWait(ctx.Duration("lock-wait"))
+		Storage.SetRates(ctx.Duration("class-ttl"), ctx.Duration("options-ttl"))
+		Storage.SetOffline(ctx.Bool("offline"))
+		if ctx.Bool("fail-fast") {
+			Storage.SetFetchMode(classsearch.FailFast)
+			Client.Mode = classsearch.FailFast
+		}
+
+		// fetch directly from site without a cache
+		Client.Offline = ctx.Bool("offline")
+		if ctx.Bool("no-cache") {
+			Client.Cache = nil
+		}
+
+		// record or replay pages fetched from site
+		if dir := ctx.String("replay"); dir != "" {
+			if ctx.String("record") != "" {
+				return ErrRecordReplay
+			}
+			Client.Fixtures = classsearch.FixtureDir{Directory: dir, Replay: true}
+		} else if dir := ctx.String("record"); dir != "" {
+			Client.Fixtures = classsearch.FixtureDir{Directory: dir}
+		}
+
+		// limit total time spent on requests
+		if timeout := ctx.Duration("timeout"); timeout > 0 {
+			RequestContext, cancelRequests = context.WithTimeout(RequestContext, timeout)
+		}
+
+		// throttle requests to site
+		Client.Limits = classsearch.NewThrottle(ctx.Int("parallel"), ctx.Float64("rate"), ctx.Duration("jitter"))
 
 		// set cache dir
 		if dir := ctx.String("directory"); dir != "" {
@@ -147,17 +538,36 @@ func main() {
 			log.Println("Directories set to", Storage.Info.Directory, "and", Storage.OptCache.Info.Directory)
 		}
 
-		// restore cache from file
-		err = Storage.Restore()
+		// choose site to fetch from, keeping sessions with the cache
+		Client.Term = ctx.String("term")
+		Client.Source, err = classsearch.OpenSource(ctx.String("source"), ctx.String("site"), Storage.Info.Directory)
 		if err != nil {
 			return
 		}
 
+		// each command opens and restores what it needs
 		return nil
 	}
 
+	app.After = func(ctx *cli.Context) (err error) {
+		printWarnings()
+		cancelRequests()
+		return Storage.Close()
+	}
+
+	// stop requests and curl children on Ctrl-C
+	var stop context.CancelFunc
+	RequestContext, stop = signal.NotifyContext(RequestContext, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Run app
 	err := app.Run(os.Args)
+	if errors.Is(err, context.Canceled) {
+		err = ErrInterrupted
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		err = ErrTimedOut
+	}
+
 	if err != nil {
 		log.SetOutput(os.Stderr)
 		log.Fatal("ERROR: " + err.Error())
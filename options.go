@@ -1,10 +0,0 @@
-package main
-
-type SearchOptions struct {
-	Terms      map[string]string
-	Divisions  map[string]string
-	Campuses   map[string]string
-	Subjects   map[string]string
-	Attributes map[string]string
-	Credits    map[string]string
-}